@@ -0,0 +1,214 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+// Outcomes of privileged commands recorded in the audit log.
+const (
+	AuditRan          = "ran"
+	AuditDenied       = "denied"
+	AuditWrongContext = "wrong context"
+	AuditBusy         = "queue full"
+	AuditPanicked     = "panicked"
+)
+
+// AuditEntry records a use of a privileged command.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	GuildID   string    `json:"guild_id,omitempty"`
+	ChannelID string    `json:"channel_id"`
+	Command   string    `json:"command"`
+	Args      string    `json:"args,omitempty"`
+	Outcome   string    `json:"outcome"`
+}
+
+// auditConfig is read from the "audit" config key.
+type auditConfig struct {
+	Channel string // channel the log is posted to, if any
+	Keep    int    // entries kept in the store
+}
+
+const (
+	auditPrefix      = "audit/"
+	defaultAuditKeep = 1000
+	defaultAuditList = 10
+)
+
+func (b *Bot) loadAuditCfg() error {
+	b.auditCfg.Keep = defaultAuditKeep
+	if b.Config.Exists("audit") {
+		err := b.Config.Get("audit", &b.auditCfg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// audit records the use of a command in the audit log if it is
+// privileged. args are the arguments it was used with.
+func (b *Bot) audit(cmd Command, name string, msg *dg.Message, args, outcome string) {
+	if !IsOwnerCommand(cmd) {
+		return
+	}
+
+	for _, a := range msg.Attachments {
+		if args != "" {
+			args += " "
+		}
+		args += "[" + a.Filename + "]"
+	}
+	e := AuditEntry{
+		Time:      time.Now(),
+		UserID:    msg.Author.ID,
+		Username:  msg.Author.Username,
+		GuildID:   msg.GuildID,
+		ChannelID: msg.ChannelID,
+		Command:   name,
+		Args:      args,
+		Outcome:   outcome,
+	}
+
+	// keys sort in the order entries were recorded
+	key := fmt.Sprintf("%s%020d", auditPrefix, e.Time.UnixNano())
+	err := b.Store.Set(key, e)
+	if err != nil {
+		b.Logf("[audit] %v", err)
+	}
+	if keys := b.Store.Keys(auditPrefix); len(keys) > b.auditCfg.Keep {
+		for _, key := range keys[:len(keys)-b.auditCfg.Keep] {
+			err := b.Store.Delete(key)
+			if err != nil {
+				b.Logf("[audit] %v", err)
+			}
+		}
+	}
+
+	if b.auditCfg.Channel != "" && b.token != "" {
+		go func() {
+			// mentions identify users without notifying them
+			_, err := DiscordSession(b.Session).ChannelMessageSendComplex(b.auditCfg.Channel, &dg.MessageSend{
+				Content:         b.auditText(e),
+				AllowedMentions: &dg.MessageAllowedMentions{},
+			})
+			if err != nil {
+				b.Logf("[audit] %v", err)
+			}
+		}()
+	}
+}
+
+// auditText describes an entry for posting to the audit channel.
+func (b *Bot) auditText(e AuditEntry) string {
+	where := "<#" + e.ChannelID + ">"
+	if e.GuildID == "" {
+		where = b.T("", "a direct message")
+	}
+	used := "`" + b.sigil + e.Command
+	if e.Args != "" {
+		used += " " + e.Args
+	}
+	used += "`"
+	return b.T("", "%s (%s) used %s in %s: %s", e.Username, "<@"+e.UserID+">", used, where, b.T("", e.Outcome))
+}
+
+// AuditLog returns the most recent n entries of the audit log for
+// which match returns true, oldest first. A nil match matches all.
+func (b *Bot) AuditLog(n int, match func(AuditEntry) bool) []AuditEntry {
+	keys := b.Store.Keys(auditPrefix)
+	var entries []AuditEntry
+	for i := len(keys) - 1; i >= 0 && len(entries) < n; i-- {
+		var e AuditEntry
+		err := b.Store.Get(keys[i], &e)
+		if err != nil {
+			b.Logf("[audit] %v", err)
+			continue
+		}
+		if match == nil || match(e) {
+			entries = append(entries, e)
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+type auditCommand struct {
+	*Bot
+}
+
+func (c auditCommand) Name() string {
+	return "audit"
+}
+
+func (c auditCommand) Comment() string {
+	return "show the audit log"
+}
+
+func (c auditCommand) Usage() []string {
+	return []string{"audit [<count>]", "audit user <user> [<count>]", "audit command <command> [<count>]"}
+}
+
+func (c auditCommand) Description() string {
+	return "Show recent uses of owner only commands: who used them, where, with what arguments and whether they ran. The log can be limited to a user or a command."
+}
+
+func (c auditCommand) Owner() {}
+
+func (c auditCommand) Execute(s Session, m *dg.Message) {
+	fields := strings.Fields(m.Content)
+	n := defaultAuditList
+	if len(fields) > 0 {
+		if i, err := strconv.Atoi(fields[len(fields)-1]); err == nil && i > 0 {
+			n = i
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	var match func(AuditEntry) bool
+	switch {
+	case len(fields) == 2 && fields[0] == "user":
+		user := strings.Trim(fields[1], "<@!>")
+		match = func(e AuditEntry) bool { return e.UserID == user || e.Username == user }
+	case len(fields) == 2 && fields[0] == "command":
+		match = func(e AuditEntry) bool { return e.Command == fields[1] }
+	case len(fields) != 0:
+		_, err := s.ChannelMessageSend(m.ChannelID, c.T(m.GuildID, "The audit log can only be limited to a user or a command."))
+		if err != nil {
+			c.Log("[audit]", err)
+		}
+		return
+	}
+
+	entries := c.AuditLog(n, match)
+	text := c.T(m.GuildID, "The audit log is empty.")
+	if len(entries) > 0 {
+		lines := make([]string, len(entries))
+		for i, e := range entries {
+			where := e.ChannelID
+			if e.GuildID == "" {
+				where = c.T(m.GuildID, "DM")
+			}
+			line := fmt.Sprintf("%s %s (%s) %s%s", e.Time.Format("2006-01-02 15:04:05"), e.Username, e.UserID, c.sigil, e.Command)
+			if e.Args != "" {
+				line += " " + e.Args
+			}
+			lines[i] = line + fmt.Sprintf(" [%s] %s", where, c.T(m.GuildID, e.Outcome))
+		}
+		text = "```\n" + strings.Join(lines, "\n") + "\n```"
+	}
+
+	err := c.ReplyText(s, m, text)
+	if err != nil {
+		c.Log("[audit]", err)
+	}
+}
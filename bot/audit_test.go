@@ -0,0 +1,101 @@
+package bot_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestAuditLog(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{
+		"owner": "owner",
+		"sigil": "!",
+		"audit": map[string]interface{}{"keep": 3},
+	})
+	b.AddCommand(bot.ToOwnerCommand(bot.SimpleCommand("say", nop, bot.SimpleCommandInfo{})))
+	b.AddCommand(bot.ToOwnerCommand(bot.ToContextCommand(bot.SimpleCommand("name", nop, bot.SimpleCommandInfo{}), bot.GuildContext)))
+	b.AddCommand(bot.SimpleCommand("roll", nop, bot.SimpleCommandInfo{}))
+
+	s := bottest.NewSession()
+	for _, m := range []*dg.Message{
+		bottest.Message("c", "owner", "!say first"),
+		bottest.Message("c", "owner", "!say hello"),
+		bottest.Message("c", "user", "!say hi"),
+		bottest.Message("c", "owner", "!roll d6"),
+		bottest.Message("dm", "owner", "!name bob"),
+	} {
+		if m.ChannelID == "c" {
+			m.GuildID = "g"
+		}
+		b.Dispatch(s, "self", m)
+	}
+
+	var got []string
+	for _, e := range b.AuditLog(10, nil) {
+		got = append(got, e.UserID+" "+e.GuildID+" "+e.Command+" "+e.Args+": "+e.Outcome)
+	}
+	want := []string{
+		"owner g say hello: ran",
+		"user g say hi: denied",
+		"owner  name bob: wrong context",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	entries := b.AuditLog(10, func(e bot.AuditEntry) bool { return e.UserID == "user" })
+	if len(entries) != 1 || entries[0].Args != "hi" {
+		t.Errorf("got %+v, want the denied entry", entries)
+	}
+}
+
+func TestAuditCommand(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	defer b.Stop()
+	b.AddCommand(bot.ToOwnerCommand(bot.SimpleCommand("say", nop, bot.SimpleCommandInfo{})))
+
+	var out bytes.Buffer
+	err := b.RunConsole(strings.NewReader("!audit\n!say hello\n!audit command say\n!audit command roll\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var found bool
+	for _, line := range lines {
+		if strings.HasSuffix(line, "!say hello [1] ran") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no entry for say in %q", lines)
+	}
+	if lines[0] != "The audit log is empty." || lines[len(lines)-1] != "The audit log is empty." {
+		t.Errorf("got %q, want empty logs first and last", lines)
+	}
+}
+
+func TestAuditCommandLong(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	b.AddCommand(bot.ToOwnerCommand(bot.SimpleCommand("say", nop, bot.SimpleCommandInfo{})))
+
+	s := bottest.NewSession()
+	for i := 0; i < 40; i++ {
+		b.Dispatch(s, "self", bottest.Message("c", "owner", "!say "+strings.Repeat("x", 60)))
+	}
+	b.Dispatch(s, "self", bottest.Message("c", "owner", "!audit 40"))
+
+	if len(s.Sent) < 2 {
+		t.Fatalf("got %d messages, want the log split", len(s.Sent))
+	}
+	for i, m := range s.Sent {
+		if n := len([]rune(m.Content)); n > bot.MaxMessageLength {
+			t.Errorf("message %d is %d long", i, n)
+		}
+	}
+}
@@ -6,41 +6,98 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
 
 	dg "github.com/bwmarrin/discordgo"
 	"github.com/njhanley/stoopid/config"
+	"github.com/njhanley/stoopid/i18n"
+	"github.com/njhanley/stoopid/store"
+	"github.com/njhanley/stoopid/template"
 	"github.com/pkg/errors"
 )
 
 type Bot struct {
-	Config  *config.Config
+	Config *config.Config
+
+	// Session is the first shard. Use ShardFor to find the shard
+	// of a guild and AddHandler to handle events from every shard.
 	Session *dg.Session
 
-	commandsMu sync.RWMutex
-	commands   map[string]Command
+	Store   *store.Store
+	Catalog *i18n.Catalog
+
+	commandsMu     sync.RWMutex
+	commands       map[string]Command
+	commandPlugins map[string]string // command name to plugin name
+	sources        []commandSource
+	loading        string // name of the plugin being loaded
 
+	loadMu    sync.Mutex // serializes loading and unloading plugins
 	pluginsMu sync.RWMutex
 	plugins   map[string]Plugin
 
+	servicesMu sync.RWMutex
+	services   map[string]interface{}
+
+	transportsMu sync.Mutex
+	transports   []Transport
+
+	replies *replyTracker
+
+	scheduler *scheduler
+	events    *eventBus
+
+	shardsMu    sync.RWMutex
+	shards      []*dg.Session
+	shardStatus []ShardStatus
+	handlers    []*eventHandler
+
+	componentsMu sync.RWMutex
+	components   map[string]func(*dg.Session, *dg.Interaction)
+
+	offersMu sync.RWMutex
+	offers   map[string]*Offer // by message ID
+
 	defers []func()
 
 	logger *log.Logger
 
 	// immutable
-	token   string
-	owner   string
-	sigil   string
-	logpath string
+	name           string // of the instance, if the process runs several
+	token          string
+	owner          string
+	sigil          string
+	logpath        string
+	slash          *slashConfig
+	editWindow     time.Duration
+	storefile      string
+	shardCount     int // 0 for the number Discord recommends
+	locales        localeConfig
+	auditCfg       auditConfig
+	ignore         ignoreLists
+	pool           *workerPool
+	panics         *panicTracker
+	templateBudget template.Budget
+	embedTheme     embedTheme
 }
 
+const defaultEditWindow = 2 * time.Minute
+
 func NewBot(cfg *config.Config) (*Bot, error) {
 	bot := &Bot{
-		Config:   cfg,
-		commands: make(map[string]Command),
-		plugins:  make(map[string]Plugin),
+		Config:         cfg,
+		commands:       make(map[string]Command),
+		commandPlugins: make(map[string]string),
+		plugins:        make(map[string]Plugin),
+		services:       make(map[string]interface{}),
+		replies:        newReplyTracker(maxInvocations),
+		scheduler:      newScheduler(),
+		events:         newEventBus(),
+		components:     make(map[string]func(*dg.Session, *dg.Interaction)),
+		offers:         make(map[string]*Offer),
 	}
 
 	err := bot.loadCfg()
@@ -48,30 +105,92 @@ func NewBot(cfg *config.Config) (*Bot, error) {
 		return nil, err
 	}
 
-	bot.Session, err = dg.New("Bot " + bot.token)
+	// set up before plugins and jobs are loaded, so what they log
+	// goes to the bot's own log
+	err = bot.initLogger()
+	if err != nil {
+		return nil, err
+	}
+
+	bot.Store, err = store.New(bot.storefile)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create session")
+		return nil, err
 	}
 
-	bot.Session.AddHandler(bot.messageCreate)
+	err = bot.loadLocales()
+	if err != nil {
+		return nil, err
+	}
 
-	bot.AddCommand(helpCommand{bot})
+	bot.Session, err = bot.newSession()
+	if err != nil {
+		return nil, err
+	}
+	bot.shards = []*dg.Session{bot.Session}
+	bot.shardStatus = make([]ShardStatus, 1)
+
+	bot.AddHandler(bot.shardConnect)
+	bot.AddHandler(bot.shardDisconnect)
+	bot.AddHandler(bot.shardReady)
+	bot.AddHandler(bot.shardResumed)
+	bot.AddHandler(bot.messageCreate)
+	bot.AddHandler(bot.messageUpdate)
+	bot.AddHandler(bot.messageDelete)
+	bot.AddHandler(bot.messageDeleteBulk)
+	bot.AddHandler(bot.interactionCreate)
+	bot.AddHandler(bot.ready)
+	bot.AddHandler(bot.publishMessage)
+	bot.AddHandler(bot.publishReactionAdd)
+	bot.AddHandler(bot.publishReactionRemove)
+	bot.AddHandler(bot.publishMemberJoin)
+	bot.AddHandler(bot.publishMemberLeave)
+	bot.AddHandler(bot.publishReady)
+	bot.AddHandler(bot.publishGuildJoin)
+
+	help := helpCommand{bot}
+	bot.AddCommand(help)
+	bot.handleComponent("help", help.component)
+
+	bot.AddCommand(jobsCommand{bot})
+	bot.AddCommand(auditCommand{bot})
+	bot.AddCommand(ignoreCommand{bot})
+	bot.AddCommand(poolCommand{bot})
+	bot.AddCommand(shardsCommand{bot})
+
+	bot.handleComponent("choice", bot.choiceComponent)
+	bot.OnReactionAdd(bot.choiceReaction)
+	bot.OnReactionRemove(bot.choiceReaction)
 
 	return bot, nil
 }
 
 func (b *Bot) loadCfg() error {
-	err := b.Config.Get("token", &b.token)
+	if b.Config.Exists("name") {
+		err := b.Config.Get("name", &b.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the token is only needed to connect to Discord
+	if b.Config.Exists("token") {
+		err := b.Config.Get("token", &b.token)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := b.Config.Get("owner", &b.owner)
 	if err != nil {
 		return err
 	}
 
-	err = b.Config.Get("owner", &b.owner)
+	err = b.Config.Get("sigil", &b.sigil)
 	if err != nil {
 		return err
 	}
 
-	err = b.Config.Get("sigil", &b.sigil)
+	err = b.loadShardCfg()
 	if err != nil {
 		return err
 	}
@@ -83,33 +202,151 @@ func (b *Bot) loadCfg() error {
 		}
 	}
 
+	// without a file, the store is not persisted
+	if b.Config.Exists("store") {
+		err = b.Config.Get("store", &b.storefile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// commands in messages edited within the window are rerun
+	b.editWindow = defaultEditWindow
+	if b.Config.Exists("editwindow") {
+		var s string
+		err = b.Config.Get("editwindow", &s)
+		if err != nil {
+			return err
+		}
+		b.editWindow, err = time.ParseDuration(s)
+		if err != nil {
+			return errors.Wrap(err, "editwindow")
+		}
+	}
+
+	err = b.loadAuditCfg()
+	if err != nil {
+		return err
+	}
+
+	err = b.loadIgnoreCfg()
+	if err != nil {
+		return err
+	}
+
+	err = b.loadPoolCfg()
+	if err != nil {
+		return err
+	}
+
+	err = b.loadPanicCfg()
+	if err != nil {
+		return err
+	}
+
+	err = b.loadTemplateCfg()
+	if err != nil {
+		return err
+	}
+
+	err = b.loadEmbedCfg()
+	if err != nil {
+		return err
+	}
+
+	if b.Config.Exists("slash") {
+		b.slash = new(slashConfig)
+		err = b.Config.Get("slash", b.slash)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (b *Bot) initLogger() error {
 	out := io.Writer(os.Stderr)
 	if b.logpath != "" {
-		file, err := os.Create(filepath.Join(b.logpath, time.Now().Format(time.RFC3339)+".log"))
+		filename := time.Now().Format(time.RFC3339) + ".log"
+		if b.name != "" {
+			filename = b.name + "-" + filename
+		}
+		file, err := os.Create(filepath.Join(b.logpath, filename))
 		if err != nil {
 			return err
 		}
 		b.Defer(func() { file.Close() })
 		out = io.MultiWriter(out, file)
 	}
-	b.logger = log.New(out, "", log.LstdFlags)
 
+	// the logs of instances sharing standard error are told apart by name
+	prefix := ""
+	if b.name != "" {
+		prefix = "[" + b.name + "] "
+	}
+	b.logger = log.New(out, prefix, log.LstdFlags)
+	return nil
+}
+
+// logDiscordgo sends what discordgo logs to the bot's log while it runs.
+func (b *Bot) logDiscordgo() {
+	addDiscordgoLogger(b)
+	b.Defer(func() { removeDiscordgoLogger(b) })
+	b.Session.LogLevel = dg.LogWarning
+}
+
+// discordgoLoggers are the bots running in the process. discordgo logs
+// through a single package variable without saying which session a
+// message is about, so its messages only go to a bot's log when there
+// is one bot; otherwise they go to standard error, once, as they belong
+// to no bot in particular.
+var (
+	discordgoLoggersMu sync.RWMutex
+	discordgoLoggers   []*Bot
+	discordgoStderr    = log.New(os.Stderr, "", log.LstdFlags)
+)
+
+func addDiscordgoLogger(b *Bot) {
+	discordgoLoggersMu.Lock()
+	defer discordgoLoggersMu.Unlock()
+	discordgoLoggers = append(discordgoLoggers, b)
+	dg.Logger = logDiscordgo
+}
+
+func removeDiscordgoLogger(b *Bot) {
+	discordgoLoggersMu.Lock()
+	defer discordgoLoggersMu.Unlock()
+	for i, l := range discordgoLoggers {
+		if l == b {
+			discordgoLoggers = append(discordgoLoggers[:i:i], discordgoLoggers[i+1:]...)
+			break
+		}
+	}
+}
+
+func logDiscordgo(level, _ int, format string, v ...interface{}) {
 	msgType := []string{
 		dg.LogError:         "ERROR",
 		dg.LogWarning:       "WARNING",
 		dg.LogInformational: "INFO",
 		dg.LogDebug:         "DEBUG",
 	}
-	dg.Logger = func(level, _ int, format string, v ...interface{}) {
-		b.Logf("[DG %s] %s\n", msgType[level], fmt.Sprintf(format, v...))
+	msg := fmt.Sprintf(format, v...)
+
+	discordgoLoggersMu.RLock()
+	defer discordgoLoggersMu.RUnlock()
+	if len(discordgoLoggers) == 1 {
+		discordgoLoggers[0].Logf("[DG %s] %s\n", msgType[level], msg)
+		return
 	}
-	b.Session.LogLevel = dg.LogWarning
+	discordgoStderr.Printf("[DG %s] %s\n", msgType[level], msg)
+}
 
-	return nil
+// Name returns the name of the bot instance, which is empty unless
+// it is one of several run by the process.
+func (b *Bot) Name() string {
+	return b.name
 }
 
 func (b *Bot) Log(v ...interface{}) {
@@ -129,20 +366,38 @@ func (b *Bot) Defer(fn func()) {
 }
 
 func (b *Bot) connect() error {
-	err := b.Session.Open()
-	if err != nil {
-		return err
-	}
-	b.Defer(func() { b.Session.Close() })
-	return nil
+	return b.openShards()
 }
 
+// Run connects the bot to Discord, if a token is configured,
+// and opens any transports that have been added.
 func (b *Bot) Run() error {
-	err := b.initLogger()
+	b.logDiscordgo()
+
+	b.transportsMu.Lock()
+	n := len(b.transports)
+	b.transportsMu.Unlock()
+
+	if b.token == "" && n == 0 {
+		return errors.New("no token configured")
+	}
+	if b.token != "" {
+		err := b.connect()
+		if err != nil {
+			return err
+		}
+	}
+	err := b.openTransports()
 	if err != nil {
 		return err
 	}
-	return b.connect()
+	b.start()
+	return nil
+}
+
+// start starts running scheduled jobs.
+func (b *Bot) start() {
+	b.startJobs()
 }
 
 func (b *Bot) Stop() {
@@ -154,21 +409,109 @@ func (b *Bot) Stop() {
 // AddPlugin loads a plugin into the bot.
 // A plugin with an empty name will have its Load method called
 // but will not be retrievable with GetPlugin.
+// If the plugin implements DependentPlugin, its dependencies
+// must already be loaded; it may look them up with GetPlugin from Load.
 func (b *Bot) AddPlugin(p Plugin) error {
-	b.pluginsMu.Lock()
-	defer b.pluginsMu.Unlock()
+	b.loadMu.Lock()
+	defer b.loadMu.Unlock()
+
+	b.pluginsMu.RLock()
+	deps, _ := dependencies(p)
+	for _, name := range deps {
+		if _, ok := b.plugins[name]; !ok {
+			b.pluginsMu.RUnlock()
+			return errors.Errorf("plugin %q requires missing plugin %q", p.Name(), name)
+		}
+	}
+	b.pluginsMu.RUnlock()
+
+	b.commandsMu.Lock()
+	b.loading = p.Name()
+	b.commandsMu.Unlock()
 
 	err := p.Load(b)
+
+	b.commandsMu.Lock()
+	b.loading = ""
+	b.commandsMu.Unlock()
+
 	if err != nil {
 		return errors.Wrapf(err, "load plugin %q failed", p.Name())
 	}
 
 	if name := p.Name(); name != "" {
+		b.pluginsMu.Lock()
 		b.plugins[name] = p
+		b.pluginsMu.Unlock()
+	}
+	return nil
+}
+
+// AddPlugins loads several plugins into the bot, ordering them so that
+// each is loaded after its dependencies. It fails without loading
+// anything if a dependency is missing or the dependencies form a cycle.
+func (b *Bot) AddPlugins(plugins ...Plugin) error {
+	sorted, err := sortPlugins(plugins, func(name string) bool { return b.GetPlugin(name) != nil })
+	if err != nil {
+		return err
+	}
+
+	for _, p := range sorted {
+		err = b.AddPlugin(p)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// UnloadPlugin unloads a plugin, removing the commands, command sources,
+// event handlers, event subscriptions and job handlers added while
+// loading it and cancelling its running jobs.
+// It fails if the plugin is not loaded or another plugin depends on it.
+func (b *Bot) UnloadPlugin(name string) error {
+	b.loadMu.Lock()
+	defer b.loadMu.Unlock()
+
+	b.pluginsMu.Lock()
+	p, ok := b.plugins[name]
+	if !ok {
+		b.pluginsMu.Unlock()
+		return errors.Errorf("plugin %q is not loaded", name)
+	}
+	for _, other := range b.plugins {
+		deps, _ := dependencies(other)
+		for _, dep := range deps {
+			if dep == name {
+				b.pluginsMu.Unlock()
+				return errors.Errorf("plugin %q is required by plugin %q", name, other.Name())
+			}
+		}
+	}
+	delete(b.plugins, name)
+	b.pluginsMu.Unlock()
+
+	if up, ok := p.(UnloadablePlugin); ok {
+		up.Unload(b)
+	}
+
+	b.commandsMu.Lock()
+	for cmd, plugin := range b.commandPlugins {
+		if plugin == name {
+			delete(b.commands, cmd)
+			delete(b.commandPlugins, cmd)
+		}
+	}
+	b.commandsMu.Unlock()
+
+	b.removeHandlers(name)
+	b.removeSources(name)
+	b.unsubscribe(name)
+	b.unloadJobs(name)
+	b.Logf("unloaded plugin %q", name)
+	return nil
+}
+
 // GetPlugin retrieves a plugin by name.
 // Nil is returned if no plugin with the given name is loaded.
 func (b *Bot) GetPlugin(name string) Plugin {
@@ -177,11 +520,69 @@ func (b *Bot) GetPlugin(name string) Plugin {
 	return b.plugins[name]
 }
 
+// AddService makes a value available to other plugins under a name.
+// Services can be replaced by adding a service with the same name.
+func (b *Bot) AddService(name string, svc interface{}) {
+	b.servicesMu.Lock()
+	b.services[name] = svc
+	b.servicesMu.Unlock()
+}
+
+// GetService stores the service with the given name in the value
+// pointed to by ptr, which is typically a pointer to an interface.
+// It returns an error if the service does not exist or
+// cannot be assigned to the value.
+func (b *Bot) GetService(name string, ptr interface{}) error {
+	b.servicesMu.RLock()
+	svc, ok := b.services[name]
+	b.servicesMu.RUnlock()
+	if !ok {
+		return errors.Errorf("service %q not found", name)
+	}
+
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.Errorf("non-pointer %T passed to GetService", ptr)
+	}
+	sv := reflect.ValueOf(svc)
+	if !sv.IsValid() || !sv.Type().AssignableTo(v.Elem().Type()) {
+		return errors.Errorf("service %q is %T, not %v", name, svc, v.Elem().Type())
+	}
+	v.Elem().Set(sv)
+	return nil
+}
+
 // AddCommand adds a command to the bot.
 // Commands can be replaced by adding a command with the same name.
+// Commands added while loading a plugin belong to the plugin.
 func (b *Bot) AddCommand(cmd Command) {
 	b.commandsMu.Lock()
+	b.addCommand(b.loading, cmd)
+	b.commandsMu.Unlock()
+}
+
+// AddPluginCommand adds a command belonging to the named plugin, as for
+// AddCommand. It is for plugins that add commands after loading.
+func (b *Bot) AddPluginCommand(plugin string, cmd Command) {
+	b.commandsMu.Lock()
+	b.addCommand(plugin, cmd)
+	b.commandsMu.Unlock()
+}
+
+func (b *Bot) addCommand(plugin string, cmd Command) {
 	b.commands[cmd.Name()] = cmd
+	if plugin != "" {
+		b.commandPlugins[cmd.Name()] = plugin
+	} else {
+		delete(b.commandPlugins, cmd.Name())
+	}
+}
+
+// RemoveCommand removes the command with a name, if there is one.
+func (b *Bot) RemoveCommand(name string) {
+	b.commandsMu.Lock()
+	delete(b.commands, name)
+	delete(b.commandPlugins, name)
 	b.commandsMu.Unlock()
 }
 
@@ -193,18 +594,100 @@ func (b *Bot) GetCommand(name string) Command {
 	return b.commands[name]
 }
 
+// CommandPlugin returns the name of the plugin that added a command,
+// or the empty string if it was not added while loading a plugin.
+func (b *Bot) CommandPlugin(name string) string {
+	b.commandsMu.RLock()
+	defer b.commandsMu.RUnlock()
+	return b.commandPlugins[name]
+}
+
+// CommandCategory returns the category a command is listed under in
+// help: its own category, if it has one, or else the plugin that added it.
+func (b *Bot) CommandCategory(name string) string {
+	b.commandsMu.RLock()
+	defer b.commandsMu.RUnlock()
+	return b.commandCategory(name, b.commands[name])
+}
+
+func (b *Bot) commandCategory(name string, cmd Command) string {
+	if found := findCommand(cmd, func(cmd Command) bool {
+		_, ok := cmd.(CategoryCommand)
+		return ok
+	}); found != nil {
+		return found.(CategoryCommand).Category()
+	}
+	if plugin := b.commandPlugins[name]; plugin != "" {
+		return plugin
+	}
+	return defaultCategory
+}
+
 // Sigil returns the string marking the beginning of a command.
 func (b *Bot) Sigil() string {
 	return b.sigil
 }
 
+// IsOwner reports whether the user with an ID owns the bot.
+func (b *Bot) IsOwner(userID string) bool {
+	return userID == b.owner
+}
+
 func (b *Bot) messageCreate(s *dg.Session, m *dg.MessageCreate) {
-	msg := m.Message
+	b.dispatchTracked(DiscordSession(s), s.State.User.ID, m.Message, nil)
+}
 
-	if msg.Author.ID == s.State.User.ID || !strings.HasPrefix(msg.Content, b.sigil) {
+// messageUpdate reruns the command in a message edited
+// within the edit window, reusing the command's replies.
+func (b *Bot) messageUpdate(s *dg.Session, m *dg.MessageUpdate) {
+	// embeds being added to a message also cause updates
+	if m.Author == nil || m.EditedTimestamp == nil {
+		return
+	}
+	if b.editWindow <= 0 || time.Since(m.Timestamp) > b.editWindow {
 		return
 	}
 
+	var old []reply
+	if inv := b.replies.get(m.ID); inv != nil {
+		if inv.content == m.Content {
+			return
+		}
+		old = inv.replies
+	}
+	b.dispatchTracked(DiscordSession(s), s.State.User.ID, m.Message, old)
+}
+
+// dispatchTracked dispatches msg, recording the replies sent by the
+// command it invokes. The replies in old are reused or deleted.
+// If msg is deleted before the command finishes, its replies are
+// deleted once it does.
+func (b *Bot) dispatchTracked(s Session, selfID string, msg *dg.Message, old []reply) {
+	content := msg.Content
+	rs := &replySession{Session: s, id: msg.ID, old: old}
+	b.replies.start(msg.ID)
+	ran := b.Dispatch(rs, selfID, msg)
+	sent := rs.finish()
+
+	// commands like say delete their invocation themselves,
+	// and that must not delete their replies
+	var inv *invocation
+	if ran && !rs.deleted {
+		inv = &invocation{id: msg.ID, content: content, replies: sent}
+	}
+	if b.replies.finish(msg.ID, inv) && inv != nil {
+		b.deleteSent(s, sent, nil)
+	}
+}
+
+// Dispatch runs the command invoked by msg, if any, and reports whether
+// a command was run. selfID is the user ID of the bot on the session
+// and is used to ignore the bot's own messages.
+func (b *Bot) Dispatch(s Session, selfID string, msg *dg.Message) bool {
+	if msg.Author.ID == selfID || !strings.HasPrefix(msg.Content, b.sigil) || b.ignoredMessage(msg) {
+		return false
+	}
+
 	msg.Content = msg.Content[len(b.sigil):]
 
 	n := strings.Index(msg.Content, " ")
@@ -214,16 +697,100 @@ func (b *Bot) messageCreate(s *dg.Session, m *dg.MessageCreate) {
 	name := msg.Content[:n]
 	msg.Content = strings.TrimSpace(msg.Content[n:])
 
-	cmd := b.GetCommand(name)
+	cmd, plugin := b.GetCommand(name), b.CommandPlugin(name)
 	if cmd == nil {
-		return
+		cmd, plugin = b.sourceCommand(msg.GuildID, name)
+	}
+	if cmd == nil {
+		return false
 	}
 
-	if IsOwnerCommand(cmd) && msg.Author.ID != b.owner {
-		b.Logf("%s was denied access to command %q", msg.Author.Username, name)
-		return
+	args := msg.Content
+	if outcome, text := b.refusal(cmd, name, msg, args); outcome != "" {
+		// users who may not use a command are not answered
+		if outcome == AuditWrongContext {
+			_, err := s.ChannelMessageSend(msg.ChannelID, text)
+			if err != nil {
+				b.Logf("%v", err)
+			}
+		}
+		return false
 	}
+	return b.runCommand(s, cmd, name, plugin, msg, args, nil)
+}
+
+// refusal checks whether msg may run cmd, named name. If it may not,
+// the refusal is audited with args and its outcome is returned
+// with the text explaining it.
+func (b *Bot) refusal(cmd Command, name string, msg *dg.Message, args string) (outcome, text string) {
+	if !b.allowed(cmd, name, msg.Author) {
+		outcome, text = AuditDenied, b.T(msg.GuildID, "You do not have permission to use that command.")
+	} else if text = b.wrongContext(cmd, msg.GuildID); text != "" {
+		outcome = AuditWrongContext
+	}
+	if outcome != "" {
+		b.audit(cmd, name, msg, args, outcome)
+	}
+	return outcome, text
+}
 
+// runCommand runs cmd, named name and belonging to plugin, for msg
+// in the worker pool, recovering from panics and telling the user with s
+// if it could not run or panicked. done, if not nil, is called once
+// nothing more will be sent with s. The outcome is then audited with
+// args and, if the command ran, published. It reports whether the
+// command was run, even if it panicked.
+func (b *Bot) runCommand(s Session, cmd Command, name, plugin string, msg *dg.Message, args string, done func()) bool {
 	b.Logf("%s used command %q", msg.Author.Username, name)
-	cmd.Execute(s, msg)
+	var panicked bool
+	err := b.pool.runCommand(msg, func() {
+		panicked = b.safely(fmt.Sprintf("command %q", name), plugin, func() { cmd.Execute(s, msg) })
+	})
+
+	outcome, text := AuditRan, ""
+	switch {
+	case err != nil:
+		b.Logf("%s could not use command %q: %v", msg.Author.Username, name, err)
+		outcome, text = AuditBusy, b.T(msg.GuildID, "Too many commands are running. Try again in a moment.")
+	case panicked:
+		outcome, text = AuditPanicked, b.T(msg.GuildID, "Something went wrong running that command.")
+	}
+	if text != "" {
+		_, err = s.ChannelMessageSend(msg.ChannelID, text)
+		if err != nil {
+			b.Logf("%v", err)
+		}
+	}
+	if done != nil {
+		done()
+	}
+
+	b.audit(cmd, name, msg, args, outcome)
+	if outcome == AuditRan {
+		b.publish(CommandEventName, &CommandEvent{Session: s, Name: name, Message: msg})
+	}
+	return outcome != AuditBusy
+}
+
+// wrongContext returns the message explaining why cmd cannot be used
+// in the guild with the given ID, or the empty string if it can.
+// An empty guild ID means a direct message.
+func (b *Bot) wrongContext(cmd Command, guildID string) string {
+	contexts := CommandContexts(cmd)
+	switch {
+	case guildID == "" && contexts&DMContext == 0:
+		return b.T(guildID, "This command can only be used in a server.")
+	case guildID != "" && contexts&GuildContext == 0:
+		return b.T(guildID, "This command can only be used in direct messages.")
+	}
+	return ""
+}
+
+// allowed reports whether user may use cmd, logging if they may not.
+func (b *Bot) allowed(cmd Command, name string, user *dg.User) bool {
+	if IsOwnerCommand(cmd) && user.ID != b.owner {
+		b.Logf("%s was denied access to command %q", user.Username, name)
+		return false
+	}
+	return true
 }
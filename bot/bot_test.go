@@ -0,0 +1,81 @@
+package bot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestDispatchContexts(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	echo := func(s bot.Session, m *dg.Message) { s.ChannelMessageSend(m.ChannelID, "ran") }
+	b.AddCommand(bot.SimpleCommand("any", echo, bot.SimpleCommandInfo{}))
+	b.AddCommand(bot.ToContextCommand(bot.SimpleCommand("guild", echo, bot.SimpleCommandInfo{}), bot.GuildContext))
+	b.AddCommand(bot.ToContextCommand(bot.SimpleCommand("dm", echo, bot.SimpleCommandInfo{}), bot.DMContext))
+
+	tests := []struct {
+		name    string
+		content string
+		guild   string
+		ran     bool
+		sent    []string
+	}{
+		{"any in guild", "!any", "g", true, []string{"ran"}},
+		{"any in dm", "!any", "", true, []string{"ran"}},
+		{"guild in guild", "!guild", "g", true, []string{"ran"}},
+		{"guild in dm", "!guild", "", false, []string{"This command can only be used in a server."}},
+		{"dm in dm", "!dm", "", true, []string{"ran"}},
+		{"dm in guild", "!dm", "g", false, []string{"This command can only be used in direct messages."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bottest.NewSession()
+			m := bottest.Message("c", "user", tt.content)
+			m.GuildID = tt.guild
+			if ran := b.Dispatch(s, "self", m); ran != tt.ran {
+				t.Errorf("ran = %v, want %v", ran, tt.ran)
+			}
+			if got := s.Contents(); !reflect.DeepEqual(got, tt.sent) {
+				t.Errorf("sent %q, want %q", got, tt.sent)
+			}
+		})
+	}
+}
+
+func TestLogWhileLoading(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stoopid-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!", "name": "one", "logpath": dir})
+	defer b.Stop()
+	err = b.AddPlugin(bot.SimplePlugin("loud", func(b *bot.Bot) error {
+		b.Log("loading")
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "one-*.log"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got log files %q, %v, want one for the bot", files, err)
+	}
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "[one] ") || !strings.HasSuffix(string(data), " loading\n") {
+		t.Errorf("got log %q, want what the plugin logged while loading", data)
+	}
+}
@@ -0,0 +1,53 @@
+package bottest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/config"
+)
+
+// NewConfig creates a config holding the given values.
+func NewConfig(t testing.TB, values map[string]interface{}) *config.Config {
+	t.Helper()
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ioutil.TempFile("", "bottest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.New(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// NewBot creates a bot configured with the given values.
+// The bot is not connected to Discord.
+func NewBot(t testing.TB, values map[string]interface{}) *bot.Bot {
+	t.Helper()
+
+	b, err := bot.NewBot(NewConfig(t, values))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
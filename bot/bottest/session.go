@@ -0,0 +1,312 @@
+// Package bottest provides utilities for testing commands.
+package bottest
+
+import (
+	"io/ioutil"
+	"strconv"
+	"sync"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+)
+
+// Session is a bot.Session that records what commands do
+// instead of talking to Discord.
+// The zero value is ready to use.
+type Session struct {
+	mu sync.Mutex
+
+	// Err, if set, is returned by every method that can fail.
+	Err error
+
+	// Sent holds the messages sent, in order.
+	// Files attached to a message are recorded as attachments
+	// and their contents are kept in Files.
+	Sent  []*dg.Message
+	Files map[string][]byte
+
+	// AllowedMentions holds the mentions allowed by each message sent,
+	// in the same order as Sent.
+	AllowedMentions []*dg.MessageAllowedMentions
+
+	// Edited holds the IDs of edited messages, in order.
+	// Edits are applied to the messages in Sent.
+	Edited []string
+
+	// Deleted holds the IDs of deleted messages, in order.
+	Deleted []string
+
+	// Reactions holds the emoji reacted to each message with, by message ID.
+	Reactions map[string][]string
+
+	// Nicknames holds the nickname set for each guild ID.
+	Nicknames map[string]string
+
+	// Permissions holds the permissions of every user in a channel,
+	// keyed by channel ID. In other channels users have every permission.
+	Permissions map[string]int64
+
+	// Avatar and Status hold the last avatar and status set.
+	Avatar, Status string
+
+	guilds   map[string]*dg.Guild
+	channels map[string]*dg.Channel
+	members  map[string]*dg.Member
+	nextID   int
+}
+
+var _ bot.Session = (*Session)(nil)
+
+// NewSession creates a Session containing the given channels.
+func NewSession(channels ...*dg.Channel) *Session {
+	s := new(Session)
+	for _, ch := range channels {
+		s.AddChannel(ch)
+	}
+	return s
+}
+
+// AddChannel makes a channel available to Channel.
+func (s *Session) AddChannel(ch *dg.Channel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.channels == nil {
+		s.channels = make(map[string]*dg.Channel)
+	}
+	s.channels[ch.ID] = ch
+}
+
+// AddGuild makes a guild available to Guild.
+func (s *Session) AddGuild(g *dg.Guild) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.guilds == nil {
+		s.guilds = make(map[string]*dg.Guild)
+	}
+	s.guilds[g.ID] = g
+}
+
+// AddMember makes a member available to GuildMember.
+func (s *Session) AddMember(mem *dg.Member) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.members == nil {
+		s.members = make(map[string]*dg.Member)
+	}
+	s.members[mem.GuildID+"/"+mem.User.ID] = mem
+}
+
+// Contents returns the text content of each message sent, in order.
+func (s *Session) Contents() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contents := make([]string, len(s.Sent))
+	for i, m := range s.Sent {
+		contents[i] = m.Content
+	}
+	return contents
+}
+
+// Embeds returns the embeds of every message sent, in order.
+func (s *Session) Embeds() []*dg.MessageEmbed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var embeds []*dg.MessageEmbed
+	for _, m := range s.Sent {
+		embeds = append(embeds, m.Embeds...)
+	}
+	return embeds
+}
+
+func (s *Session) ChannelMessageSend(channelID, content string) (*dg.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &dg.MessageSend{Content: content})
+}
+
+func (s *Session) ChannelMessageSendEmbed(channelID string, embed *dg.MessageEmbed) (*dg.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &dg.MessageSend{Embeds: []*dg.MessageEmbed{embed}})
+}
+
+func (s *Session) ChannelMessageSendComplex(channelID string, data *dg.MessageSend) (*dg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	s.nextID++
+	m := &dg.Message{
+		ID:         "sent" + strconv.Itoa(s.nextID),
+		ChannelID:  channelID,
+		Content:    data.Content,
+		Embeds:     data.Embeds,
+		Components: data.Components,
+	}
+	for _, f := range data.Files {
+		b, err := ioutil.ReadAll(f.Reader)
+		if err != nil {
+			return nil, err
+		}
+		if s.Files == nil {
+			s.Files = make(map[string][]byte)
+		}
+		s.Files[f.Name] = b
+		m.Attachments = append(m.Attachments, &dg.MessageAttachment{Filename: f.Name, Size: len(b)})
+	}
+	s.Sent = append(s.Sent, m)
+	s.AllowedMentions = append(s.AllowedMentions, data.AllowedMentions)
+	return m, nil
+}
+
+func (s *Session) ChannelMessageEditComplex(m *dg.MessageEdit) (*dg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	for _, sent := range s.Sent {
+		if sent.ID != m.ID || sent.ChannelID != m.Channel {
+			continue
+		}
+		if m.Content != nil {
+			sent.Content = *m.Content
+		}
+		if m.Embeds != nil {
+			sent.Embeds = *m.Embeds
+		}
+		if m.Components != nil {
+			sent.Components = *m.Components
+		}
+		s.Edited = append(s.Edited, m.ID)
+		return sent, nil
+	}
+	return nil, dg.ErrStateNotFound
+}
+
+func (s *Session) ChannelMessageDelete(channelID, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	s.Deleted = append(s.Deleted, messageID)
+	return nil
+}
+
+func (s *Session) MessageReactionAdd(channelID, messageID, emojiID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if s.Reactions == nil {
+		s.Reactions = make(map[string][]string)
+	}
+	s.Reactions[messageID] = append(s.Reactions[messageID], emojiID)
+	return nil
+}
+
+func (s *Session) MessageReactionsRemoveAll(channelID, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	delete(s.Reactions, messageID)
+	return nil
+}
+
+func (s *Session) Channel(channelID string) (*dg.Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	ch, ok := s.channels[channelID]
+	if !ok {
+		return nil, dg.ErrStateNotFound
+	}
+	return ch, nil
+}
+
+func (s *Session) Guild(guildID string) (*dg.Guild, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	g, ok := s.guilds[guildID]
+	if !ok {
+		return nil, dg.ErrStateNotFound
+	}
+	return g, nil
+}
+
+func (s *Session) GuildMember(guildID, userID string) (*dg.Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	mem, ok := s.members[guildID+"/"+userID]
+	if !ok {
+		return nil, dg.ErrStateNotFound
+	}
+	return mem, nil
+}
+
+func (s *Session) GuildMemberNickname(guildID, userID, nickname string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	if s.Nicknames == nil {
+		s.Nicknames = make(map[string]string)
+	}
+	s.Nicknames[guildID] = nickname
+	return nil
+}
+
+func (s *Session) UserChannelPermissions(userID, channelID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	perms, ok := s.Permissions[channelID]
+	if !ok {
+		return dg.PermissionAll, nil
+	}
+	return perms, nil
+}
+
+func (s *Session) SetAvatar(avatar string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	s.Avatar = avatar
+	return nil
+}
+
+func (s *Session) SetStatus(game string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	s.Status = game
+	return nil
+}
+
+// Message creates a message as it would be passed to Execute.
+func Message(channelID, authorID, content string) *dg.Message {
+	return &dg.Message{
+		ID:        "msg",
+		ChannelID: channelID,
+		Content:   content,
+		Author:    &dg.User{ID: authorID, Username: "user" + authorID},
+	}
+}
@@ -0,0 +1,394 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// DefaultChoiceTimeout is how long to wait for a choice
+// if a prompt does not say.
+const DefaultChoiceTimeout = 2 * time.Minute
+
+var (
+	// ErrChoiceTimeout is returned when no choice is made in time.
+	ErrChoiceTimeout = errors.New("timed out waiting for a choice")
+	// ErrOfferClosed is returned when waiting for a choice
+	// from an offer that has been closed.
+	ErrOfferClosed = errors.New("offer closed")
+)
+
+// Choice is one of the choices offered by a message.
+type Choice struct {
+	ID       string         // returned when the choice is made
+	Label    string         // text of the choice's button
+	Emoji    string         // the choice's reaction, also shown on its button
+	Style    dg.ButtonStyle // style of the choice's button, secondary by default
+	Disabled bool           // whether the choice's button is disabled
+}
+
+// Prompt describes how choices are offered.
+type Prompt struct {
+	// UserID is the user whose choices count.
+	// If it is empty, anyone may choose.
+	UserID string
+
+	// Reactions offers the choices as reactions instead of buttons.
+	// Reactions work where buttons cannot be used, but choices
+	// cannot be disabled and are limited to twenty.
+	Reactions bool
+
+	// Timeout is how long to wait for each choice.
+	// Zero means DefaultChoiceTimeout.
+	Timeout time.Duration
+}
+
+// Offer is a message offering choices, as sent by Bot.Offer.
+type Offer struct {
+	b       *Bot
+	s       Session
+	msg     *dg.Message
+	prompt  Prompt
+	choices chan string
+
+	mu      sync.Mutex
+	offered []Choice
+	closed  bool
+}
+
+// choiceButtons and choiceRows limit the buttons in a message.
+const (
+	choiceButtons = 5 // per row
+	choiceRows    = 5
+	maxReactions  = 20
+)
+
+// Offer sends a message offering choices to a user. The caller waits
+// for choices with Next and must call Close when it is done, which
+// removes the choices from the message.
+func (b *Bot) Offer(s Session, channelID string, data *dg.MessageSend, choices []Choice, p Prompt) (*Offer, error) {
+	if len(choices) == 0 {
+		return nil, errors.New("no choices offered")
+	}
+	if p.Reactions && len(choices) > maxReactions {
+		return nil, errors.Errorf("%d choices offered as reactions, more than %d", len(choices), maxReactions)
+	}
+	if !p.Reactions && len(choices) > choiceButtons*choiceRows {
+		return nil, errors.Errorf("%d choices offered as buttons, more than %d", len(choices), choiceButtons*choiceRows)
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultChoiceTimeout
+	}
+
+	send := *data
+	if !p.Reactions {
+		send.Components = choiceComponents(choices)
+	}
+	msg, err := s.ChannelMessageSendComplex(channelID, &send)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &Offer{
+		b:       b,
+		s:       s,
+		msg:     msg,
+		prompt:  p,
+		choices: make(chan string, 1),
+		offered: choices,
+	}
+	b.offersMu.Lock()
+	b.offers[msg.ID] = o
+	b.offersMu.Unlock()
+
+	if p.Reactions {
+		for _, c := range choices {
+			err := s.MessageReactionAdd(msg.ChannelID, msg.ID, c.Emoji)
+			if err != nil {
+				o.Close()
+				return nil, err
+			}
+		}
+	}
+	return o, nil
+}
+
+// choiceComponents lays out choices as rows of buttons.
+func choiceComponents(choices []Choice) []dg.MessageComponent {
+	var rows []dg.MessageComponent
+	for len(choices) > 0 {
+		n := len(choices)
+		if n > choiceButtons {
+			n = choiceButtons
+		}
+		row := dg.ActionsRow{}
+		for _, c := range choices[:n] {
+			style := c.Style
+			if style == 0 {
+				style = dg.SecondaryButton
+			}
+			button := dg.Button{
+				Label:    c.Label,
+				Style:    style,
+				Disabled: c.Disabled,
+				CustomID: "choice:" + c.ID,
+			}
+			if c.Emoji != "" {
+				button.Emoji = &dg.ComponentEmoji{Name: c.Emoji}
+				if n := strings.LastIndex(c.Emoji, ":"); n >= 0 {
+					button.Emoji = &dg.ComponentEmoji{Name: c.Emoji[:n], ID: c.Emoji[n+1:]}
+				}
+			}
+			row.Components = append(row.Components, button)
+		}
+		rows = append(rows, row)
+		choices = choices[n:]
+	}
+	return rows
+}
+
+// Message returns the message offering the choices.
+func (o *Offer) Message() *dg.Message {
+	return o.msg
+}
+
+// Next waits for a choice and returns its ID. A command waiting here
+// keeps its worker; Confirm and Menu lend theirs to other commands.
+func (o *Offer) Next() (string, error) {
+	timer := time.NewTimer(o.prompt.Timeout)
+	defer timer.Stop()
+	select {
+	case id, ok := <-o.choices:
+		if !ok {
+			return "", ErrOfferClosed
+		}
+		return id, nil
+	case <-timer.C:
+		return "", ErrChoiceTimeout
+	}
+}
+
+// Update edits the message offering the choices. If choices is not nil,
+// it replaces the choices offered. Choices offered as reactions
+// cannot be replaced, only disabled by leaving them out.
+func (o *Offer) Update(data *dg.MessageSend, choices []Choice) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrOfferClosed
+	}
+	if choices != nil {
+		o.offered = choices
+	}
+
+	edit := dg.NewMessageEdit(o.msg.ChannelID, o.msg.ID)
+	edit.Content = &data.Content
+	edit.Embeds = &data.Embeds
+	if !o.prompt.Reactions {
+		components := choiceComponents(o.offered)
+		edit.Components = &components
+	}
+	_, err := o.s.ChannelMessageEditComplex(edit)
+	return err
+}
+
+// Close stops offering the choices and removes them from the message.
+// Calling Close more than once has no effect.
+func (o *Offer) Close() {
+	o.b.offersMu.Lock()
+	if o.b.offers[o.msg.ID] == o {
+		delete(o.b.offers, o.msg.ID)
+	}
+	o.b.offersMu.Unlock()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	o.closed = true
+	close(o.choices)
+
+	var err error
+	if o.prompt.Reactions {
+		err = o.s.MessageReactionsRemoveAll(o.msg.ChannelID, o.msg.ID)
+	} else {
+		edit := dg.NewMessageEdit(o.msg.ChannelID, o.msg.ID)
+		edit.Components = &[]dg.MessageComponent{}
+		_, err = o.s.ChannelMessageEditComplex(edit)
+	}
+	if err != nil {
+		o.b.Logf("[choice] %v", err)
+	}
+}
+
+// choose delivers a user's choice to the offer in a message and
+// reports whether there is an offer the user may choose from.
+// Choices made while an earlier one is being handled are dropped.
+func (b *Bot) choose(messageID, userID string, match func(Choice) bool) (found, allowed bool) {
+	b.offersMu.RLock()
+	o := b.offers[messageID]
+	b.offersMu.RUnlock()
+	if o == nil {
+		return false, false
+	}
+	if o.prompt.UserID != "" && o.prompt.UserID != userID {
+		return true, false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return false, false
+	}
+	for _, c := range o.offered {
+		if match(c) && !c.Disabled {
+			select {
+			case o.choices <- c.ID:
+			default:
+			}
+			break
+		}
+	}
+	return true, true
+}
+
+// choiceComponent handles the buttons of offers.
+func (b *Bot) choiceComponent(s *dg.Session, i *dg.Interaction) {
+	id := strings.TrimPrefix(i.MessageComponentData().CustomID, "choice:")
+	var userID string
+	if user := interactionUser(i); user != nil {
+		userID = user.ID
+	}
+
+	found, allowed := b.choose(i.Message.ID, userID, func(c Choice) bool { return c.ID == id })
+	switch {
+	case !found:
+		b.respondEphemeral(s, i, b.T(i.GuildID, "This has expired."))
+		return
+	case !allowed:
+		b.respondEphemeral(s, i, b.T(i.GuildID, "Only the person this was for can choose."))
+		return
+	}
+
+	err := s.InteractionRespond(i, &dg.InteractionResponse{Type: dg.InteractionResponseDeferredMessageUpdate})
+	if err != nil {
+		b.Logf("failed to respond to interaction: %v", err)
+	}
+}
+
+// choiceReaction handles the reactions of offers. Removing
+// a reaction counts as choosing it too, so that a choice
+// can be made again without removing the reaction first.
+func (b *Bot) choiceReaction(e *ReactionEvent) {
+	r := e.Reaction
+	b.choose(r.MessageID, r.UserID, func(c Choice) bool {
+		return c.Emoji == r.Emoji.Name || c.Emoji == r.Emoji.APIName()
+	})
+}
+
+// Confirm asks the user who sent m a yes or no question
+// and reports whether they answered yes.
+func (b *Bot) Confirm(s Session, m *dg.Message, question string) (bool, error) {
+	o, err := b.Offer(s, m.ChannelID, &dg.MessageSend{Content: question}, []Choice{
+		{ID: "yes", Label: b.T(m.GuildID, "Yes"), Style: dg.SuccessButton},
+		{ID: "no", Label: b.T(m.GuildID, "No"), Style: dg.DangerButton},
+	}, Prompt{UserID: m.Author.ID})
+	if err != nil {
+		return false, err
+	}
+	defer o.Close()
+
+	id, err := b.next(m, o)
+	return id == "yes", err
+}
+
+// Menu asks the user who sent m to choose one of several options
+// and returns the index of the option chosen.
+func (b *Bot) Menu(s Session, m *dg.Message, text string, options []string) (int, error) {
+	choices := make([]Choice, len(options))
+	for i, opt := range options {
+		label := opt
+		if r := []rune(label); len(r) > 80 {
+			label = string(r[:79]) + "…"
+		}
+		choices[i] = Choice{ID: strconv.Itoa(i), Label: label}
+	}
+
+	o, err := b.Offer(s, m.ChannelID, &dg.MessageSend{Content: text}, choices, Prompt{UserID: m.Author.ID})
+	if err != nil {
+		return -1, err
+	}
+	defer o.Close()
+
+	id, err := b.next(m, o)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(id)
+}
+
+// next waits for a choice from an offer made by the command invoked
+// by m, freeing the command's worker meanwhile so that commands
+// waiting on users do not hold up the others.
+func (b *Bot) next(m *dg.Message, o *Offer) (id string, err error) {
+	b.pool.idle(m, func() { id, err = o.Next() })
+	return id, err
+}
+
+// Paginate sends the first of several pages with buttons letting the
+// user who sent m turn the pages. It returns once the first page is sent
+// and removes the buttons after the user has not turned a page for
+// DefaultChoiceTimeout.
+func (b *Bot) Paginate(s Session, m *dg.Message, pages []*dg.MessageSend) error {
+	switch len(pages) {
+	case 0:
+		return errors.New("no pages")
+	case 1:
+		_, err := s.ChannelMessageSendComplex(m.ChannelID, pages[0])
+		return err
+	}
+
+	buttons := func(page int) []Choice {
+		return []Choice{
+			{ID: "prev", Label: "◀", Disabled: page == 0},
+			{ID: "page", Label: b.T(m.GuildID, "Page %d/%d", page+1, len(pages)), Disabled: true},
+			{ID: "next", Label: "▶", Disabled: page == len(pages)-1},
+		}
+	}
+
+	o, err := b.Offer(s, m.ChannelID, pages[0], buttons(0), Prompt{UserID: m.Author.ID})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer o.Close()
+		page := 0
+		for {
+			id, err := o.Next()
+			if err != nil {
+				return
+			}
+			switch id {
+			case "prev":
+				page--
+			case "next":
+				page++
+			}
+			if page < 0 || page >= len(pages) {
+				page = (page + len(pages)) % len(pages)
+			}
+			err = o.Update(pages[page], buttons(page))
+			if err != nil {
+				b.Logf("[choice] %v", err)
+				return
+			}
+		}
+	}()
+	return nil
+}
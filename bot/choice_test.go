@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/i18n"
+	"github.com/njhanley/stoopid/store"
+)
+
+func newChoiceBot() *Bot {
+	st, _ := store.New("")
+	return &Bot{
+		Store:   st,
+		Catalog: new(i18n.Catalog),
+		offers:  make(map[string]*Offer),
+		pool:    newWorkerPool(defaultWorkers, defaultQueueSize),
+		logger:  log.New(ioutil.Discard, "", 0),
+	}
+}
+
+// waitForOffer waits until a message is offering choices.
+func waitForOffer(t *testing.T, b *Bot, messageID string) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		b.offersMu.RLock()
+		o := b.offers[messageID]
+		b.offersMu.RUnlock()
+		if o != nil {
+			return
+		}
+	}
+	t.Fatalf("no offer in message %s", messageID)
+}
+
+func byID(id string) func(Choice) bool {
+	return func(c Choice) bool { return c.ID == id }
+}
+
+func TestConfirm(t *testing.T) {
+	b := newChoiceBot()
+	fs := new(fakeSession)
+	m := &dg.Message{ChannelID: "c", Author: &dg.User{ID: "u"}}
+
+	answer := make(chan bool)
+	go func() {
+		ok, err := b.Confirm(fs, m, "Really?")
+		if err != nil {
+			t.Error(err)
+		}
+		answer <- ok
+	}()
+
+	waitForOffer(t, b, "m1")
+	if found, allowed := b.choose("m1", "someone else", byID("no")); !found || allowed {
+		t.Errorf("another user's choice: found %v, allowed %v", found, allowed)
+	}
+	b.choose("m1", "u", byID("yes"))
+	if !<-answer {
+		t.Error("yes was not confirmed")
+	}
+
+	want := []string{"send m1 Really?", "edit m1 0 components"}
+	if got := fs.entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if found, _ := b.choose("m1", "u", byID("yes")); found {
+		t.Error("choice accepted after the offer closed")
+	}
+}
+
+func TestOfferReactions(t *testing.T) {
+	b := newChoiceBot()
+	fs := new(fakeSession)
+	choices := []Choice{{ID: "up", Emoji: "👍"}, {ID: "custom", Emoji: "party:123"}}
+	o, err := b.Offer(fs, "c", &dg.MessageSend{Content: "vote"}, choices, Prompt{Reactions: true, Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.choiceReaction(&ReactionEvent{Reaction: &dg.MessageReaction{
+		MessageID: "m1", UserID: "anyone", Emoji: dg.Emoji{ID: "123", Name: "party"},
+	}})
+	if id, err := o.Next(); id != "custom" || err != nil {
+		t.Errorf("got %q, %v, want the custom emoji", id, err)
+	}
+	if _, err := o.Next(); err != ErrChoiceTimeout {
+		t.Errorf("got error %v, want a timeout", err)
+	}
+	o.Close()
+	o.Close()
+	if _, err := o.Next(); err != ErrOfferClosed {
+		t.Errorf("got error %v after closing, want %v", err, ErrOfferClosed)
+	}
+
+	want := []string{"send m1 vote", "react m1 👍", "react m1 party:123", "unreact m1"}
+	if got := fs.entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	b := newChoiceBot()
+	fs := new(fakeSession)
+	m := &dg.Message{ChannelID: "c", Author: &dg.User{ID: "u"}}
+	pages := []*dg.MessageSend{{Content: "one"}, {Content: "two"}, {Content: "three"}}
+
+	err := b.Paginate(fs, m, pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForOffer(t, b, "m1")
+
+	// a disabled button cannot be chosen
+	b.choose("m1", "u", byID("prev"))
+	b.choose("m1", "u", byID("next"))
+	want := []string{"send m1 one", "edit m1 two"}
+	for start := time.Now(); time.Since(start) < time.Second && len(fs.entries()) < len(want); {
+		time.Sleep(time.Millisecond)
+	}
+	if got := fs.entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestChoiceComponents(t *testing.T) {
+	choices := make([]Choice, 7)
+	for i := range choices {
+		choices[i] = Choice{ID: string(rune('a' + i)), Label: "x"}
+	}
+	choices[6].Emoji = "party:123"
+
+	rows := choiceComponents(choices)
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	last := rows[1].(dg.ActionsRow).Components
+	if len(last) != 2 {
+		t.Fatalf("got %d buttons in the last row, want 2", len(last))
+	}
+	button := last[1].(dg.Button)
+	if button.CustomID != "choice:g" || button.Emoji == nil || button.Emoji.Name != "party" || button.Emoji.ID != "123" {
+		t.Errorf("got button %+v", button)
+	}
+}
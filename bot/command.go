@@ -1,9 +1,6 @@
 package bot
 
 import (
-	"sort"
-	"strings"
-
 	dg "github.com/bwmarrin/discordgo"
 )
 
@@ -19,7 +16,29 @@ type Command interface {
 	Comment() string     // a short description
 	Usage() []string     // command syntax
 	Description() string // a detailed description
-	Execute(*dg.Session, *dg.Message)
+	Execute(Session, *dg.Message)
+}
+
+// decorator is implemented by commands that wrap another command,
+// such as those returned by ToHiddenCommand and ToOwnerCommand.
+type decorator interface {
+	Unwrap() Command
+}
+
+// findCommand returns the first of cmd and the commands it
+// decorates for which match is true, or nil if there is none.
+func findCommand(cmd Command, match func(Command) bool) Command {
+	for cmd != nil {
+		if match(cmd) {
+			return cmd
+		}
+		d, ok := cmd.(decorator)
+		if !ok {
+			return nil
+		}
+		cmd = d.Unwrap()
+	}
+	return nil
 }
 
 // HiddenCommand is an interface for
@@ -29,16 +48,21 @@ type HiddenCommand interface {
 	Hidden()
 }
 
-// IsHiddenCommand reports if a command implements HiddenCommand.
+// IsHiddenCommand reports if a command, or any
+// command it decorates, implements HiddenCommand.
 func IsHiddenCommand(cmd Command) bool {
-	_, ok := cmd.(HiddenCommand)
-	return ok
+	return findCommand(cmd, func(cmd Command) bool {
+		_, ok := cmd.(HiddenCommand)
+		return ok
+	}) != nil
 }
 
 type hiddenCommand struct{ Command }
 
 func (c hiddenCommand) Hidden() {}
 
+func (c hiddenCommand) Unwrap() Command { return c.Command }
+
 // ToHiddenCommand decorates a command with
 // a Hidden method, implementing HiddenCommand.
 func ToHiddenCommand(cmd Command) HiddenCommand {
@@ -52,173 +76,245 @@ type OwnerCommand interface {
 	Owner()
 }
 
-// IsOwnerCommand reports if a command implements OwnerCommand.
+// IsOwnerCommand reports if a command, or any
+// command it decorates, implements OwnerCommand.
 func IsOwnerCommand(cmd Command) bool {
-	_, ok := cmd.(OwnerCommand)
-	return ok
+	return findCommand(cmd, func(cmd Command) bool {
+		_, ok := cmd.(OwnerCommand)
+		return ok
+	}) != nil
 }
 
 type ownerCommand struct{ Command }
 
 func (c ownerCommand) Owner() {}
 
+func (c ownerCommand) Unwrap() Command { return c.Command }
+
 // ToOwnerCommand decorates a command with
 // an Owner method, implementing OwnerCommand.
 func ToOwnerCommand(cmd Command) OwnerCommand {
 	return ownerCommand{cmd}
 }
 
-// SimpleCommandInfo specifies the help information for SimpleCommand.
-type SimpleCommandInfo struct {
-	Comment     string
-	Usage       []string
-	Description string
+// CommandContext is a set of places where a command may be used.
+type CommandContext int
+
+const (
+	GuildContext CommandContext = 1 << iota // channels in guilds
+	DMContext                               // direct messages
+
+	AnyContext = GuildContext | DMContext
+)
+
+// IsDM reports whether a message is a direct message.
+func IsDM(m *dg.Message) bool {
+	return m.GuildID == ""
 }
 
-// ExecuteFunc is a function that implements Execute for SimpleCommand.
-type ExecuteFunc func(*dg.Session, *dg.Message)
+// ContextCommand is an interface for commands
+// that may only be used in some contexts.
+// Commands that do not declare their contexts may be used anywhere.
+type ContextCommand interface {
+	Command
+	Contexts() CommandContext
+}
 
-// SimpleCommand is a convenience function
-// for creating commands from functions.
-func SimpleCommand(name string, fn ExecuteFunc, i SimpleCommandInfo) Command {
-	return &simpleCommand{name: name, exec: fn, info: i}
+// CommandContexts returns the contexts declared by a command, or
+// any command it decorates, or AnyContext if none are declared.
+func CommandContexts(cmd Command) CommandContext {
+	found := findCommand(cmd, func(cmd Command) bool {
+		_, ok := cmd.(ContextCommand)
+		return ok
+	})
+	if found == nil {
+		return AnyContext
+	}
+	return found.(ContextCommand).Contexts()
 }
 
-type simpleCommand struct {
-	name string
-	exec ExecuteFunc
-	info SimpleCommandInfo
+type contextCommand struct {
+	Command
+	contexts CommandContext
 }
 
-func (c *simpleCommand) Name() string {
-	return c.name
+func (c contextCommand) Contexts() CommandContext { return c.contexts }
+
+func (c contextCommand) Unwrap() Command { return c.Command }
+
+// ToContextCommand decorates a command with a
+// Contexts method, implementing ContextCommand.
+func ToContextCommand(cmd Command, contexts CommandContext) ContextCommand {
+	return contextCommand{cmd, contexts}
 }
 
-func (c *simpleCommand) Comment() string {
-	return c.info.Comment
+// AliasCommand is an interface for commands
+// that are another name for some command.
+type AliasCommand interface {
+	Command
+	AliasOf() string
 }
 
-func (c *simpleCommand) Usage() []string {
-	// create copy to enforce immutability
-	return append([]string(nil), c.info.Usage...)
+// CommandAliasOf returns the name of the command that a command, or any
+// command it decorates, is an alias of, or the empty string if none.
+func CommandAliasOf(cmd Command) string {
+	found := findCommand(cmd, func(cmd Command) bool {
+		_, ok := cmd.(AliasCommand)
+		return ok
+	})
+	if found == nil {
+		return ""
+	}
+	return found.(AliasCommand).AliasOf()
 }
 
-func (c *simpleCommand) Description() string {
-	return c.info.Description
+type aliasCommand struct {
+	Command
+	of string
 }
 
-func (c *simpleCommand) Execute(s *dg.Session, m *dg.Message) {
-	c.exec(s, m)
+func (c aliasCommand) AliasOf() string { return c.of }
+
+func (c aliasCommand) Unwrap() Command { return c.Command }
+
+// ToAliasCommand decorates a command with an
+// AliasOf method, implementing AliasCommand.
+func ToAliasCommand(cmd Command, of string) AliasCommand {
+	return aliasCommand{cmd, of}
 }
 
-type helpCommand struct {
-	*Bot
+// CategoryCommand is an interface for commands that are listed in help
+// under a category other than the name of the plugin that added them.
+type CategoryCommand interface {
+	Command
+	Category() string
 }
 
-func (c helpCommand) Name() string {
-	return "help"
+type categoryCommand struct {
+	Command
+	category string
 }
 
-func (c helpCommand) Comment() string {
-	return "get info about commands"
+func (c categoryCommand) Category() string { return c.category }
+
+func (c categoryCommand) Unwrap() Command { return c.Command }
+
+// ToCategoryCommand decorates a command with a
+// Category method, implementing CategoryCommand.
+func ToCategoryCommand(cmd Command, category string) CategoryCommand {
+	return categoryCommand{cmd, category}
 }
 
-func (c helpCommand) Usage() []string {
-	return []string{"help [<command>]"}
+// ArgumentType is the type of a command argument.
+type ArgumentType int
+
+const (
+	StringArgument ArgumentType = iota
+	IntegerArgument
+	NumberArgument
+	BooleanArgument
+	UserArgument
+	ChannelArgument
+	AttachmentArgument
+)
+
+// Argument describes an argument of a command and is used to
+// build the command's slash command. When a slash command is used,
+// the values of its arguments are joined by spaces, in order, to form
+// the message content passed to Execute. User and channel arguments
+// are given as mentions, and attachment arguments are added to the
+// message's attachments instead of its content.
+type Argument struct {
+	Name        string
+	Description string
+	Type        ArgumentType
+	Required    bool
+
+	// Complete, if set, suggests values for a partially typed
+	// string argument. At most 25 suggestions are shown.
+	Complete func(partial string) []string
 }
 
-func (c helpCommand) Description() string {
-	return "Get information about commands. If a command is not specified, list all commands."
+// ArgumentCommand is an interface for
+// commands that declare their arguments.
+// Commands that do not declare their arguments are
+// given a single optional string argument named "text".
+type ArgumentCommand interface {
+	Command
+	Arguments() []Argument
 }
 
-func (c helpCommand) Execute(s *dg.Session, m *dg.Message) {
-	var err error
-	if m.Content == "" {
-		c.helplist(s, m)
-	} else {
-		c.help(s, m)
-	}
-	if err != nil {
-		c.Log("[help]", err)
+var defaultArguments = []Argument{{Name: "text", Description: "arguments"}}
+
+// CommandArguments returns the arguments declared by a command, or any
+// command it decorates, or the default arguments if none are declared.
+func CommandArguments(cmd Command) []Argument {
+	found := findCommand(cmd, func(cmd Command) bool {
+		_, ok := cmd.(ArgumentCommand)
+		return ok
+	})
+	if found == nil {
+		return defaultArguments
 	}
+	return found.(ArgumentCommand).Arguments()
 }
 
-const missingText = "<undefined>"
+type argumentCommand struct {
+	Command
+	args []Argument
+}
 
-func (c helpCommand) help(s *dg.Session, m *dg.Message) error {
-	cmd := c.GetCommand(m.Content)
-	if cmd == nil {
-		_, err := s.ChannelMessageSend(m.ChannelID, "Command not found.")
-		return err
-	}
+func (c argumentCommand) Arguments() []Argument {
+	return append([]Argument(nil), c.args...)
+}
 
-	ownercmd := IsOwnerCommand(cmd)
-	if ownercmd && m.Author.ID != c.owner {
-		_, err := s.ChannelMessageSend(m.ChannelID, "You do not have permission to use that command.")
-		return err
-	}
+func (c argumentCommand) Unwrap() Command { return c.Command }
 
-	usages := cmd.Usage()
-	for i, s := range usages {
-		usages[i] = "`" + c.sigil + s + "`"
-	}
-	usage := strings.Join(usages, "\n")
-	if usage == "" {
-		usage = missingText
-	}
+// ToArgumentCommand decorates a command with an
+// Arguments method, implementing ArgumentCommand.
+func ToArgumentCommand(cmd Command, args ...Argument) ArgumentCommand {
+	return argumentCommand{cmd, args}
+}
 
-	description := cmd.Description()
-	if description == "" {
-		description = missingText
-	}
-	if ownercmd {
-		description += "\n\nOwner only."
-	}
+// SimpleCommandInfo specifies the help information for SimpleCommand.
+type SimpleCommandInfo struct {
+	Comment     string
+	Usage       []string
+	Description string
+}
 
-	fields := []*dg.MessageEmbedField{
-		{Name: "Usage:", Value: usage},
-		{Name: "Description:", Value: description},
-	}
+// ExecuteFunc is a function that implements Execute for SimpleCommand.
+type ExecuteFunc func(Session, *dg.Message)
 
-	_, err := s.ChannelMessageSendEmbed(m.ChannelID, &dg.MessageEmbed{
-		Fields: fields,
-		Footer: &dg.MessageEmbedFooter{
-			Text: "For a list of all commands, use " + c.sigil + "help",
-		},
-	})
-	return err
+// SimpleCommand is a convenience function
+// for creating commands from functions.
+func SimpleCommand(name string, fn ExecuteFunc, i SimpleCommandInfo) Command {
+	return &simpleCommand{name: name, exec: fn, info: i}
 }
 
-func (c helpCommand) helplist(s *dg.Session, m *dg.Message) error {
-	c.commandsMu.RLock()
-	defer c.commandsMu.RUnlock()
+type simpleCommand struct {
+	name string
+	exec ExecuteFunc
+	info SimpleCommandInfo
+}
 
-	commands := make([]*dg.MessageEmbedField, 0, len(c.commands))
-	for name, cmd := range c.commands {
-		ownercmd := IsOwnerCommand(cmd)
-		if IsHiddenCommand(cmd) || (ownercmd && m.Author.ID != c.owner) {
-			continue
-		}
+func (c *simpleCommand) Name() string {
+	return c.name
+}
 
-		command := &dg.MessageEmbedField{Name: name, Value: cmd.Comment()}
-		if command.Name == "" {
-			command.Name = missingText
-		}
-		if command.Value == "" {
-			command.Value = missingText
-		}
-		if ownercmd {
-			command.Value += "\nowner only"
-		}
-		commands = append(commands, command)
-	}
+func (c *simpleCommand) Comment() string {
+	return c.info.Comment
+}
+
+func (c *simpleCommand) Usage() []string {
+	// create copy to enforce immutability
+	return append([]string(nil), c.info.Usage...)
+}
 
-	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+func (c *simpleCommand) Description() string {
+	return c.info.Description
+}
 
-	_, err := s.ChannelMessageSendEmbed(m.ChannelID, &dg.MessageEmbed{
-		Title:  "Commands:",
-		Fields: commands,
-		Footer: &dg.MessageEmbedFooter{Text: "For more information, use " + c.sigil + "help <command>"},
-	})
-	return err
+func (c *simpleCommand) Execute(s Session, m *dg.Message) {
+	c.exec(s, m)
 }
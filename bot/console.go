@@ -0,0 +1,232 @@
+package bot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+type consoleConfig struct {
+	User    struct{ ID, Name string }
+	Channel struct{ ID, Name string }
+	Guild   struct{ ID, Name string }
+}
+
+func (b *Bot) loadConsoleCfg() (*consoleConfig, error) {
+	var cfg consoleConfig
+	if b.Config.Exists("console") {
+		err := b.Config.Get("console", &cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.User.ID == "" {
+		cfg.User.ID = b.owner
+	}
+	if cfg.User.Name == "" {
+		cfg.User.Name = "console"
+	}
+	if cfg.Channel.ID == "" {
+		cfg.Channel.ID = "1"
+	}
+	if cfg.Channel.Name == "" {
+		cfg.Channel.Name = "console"
+	}
+	if cfg.Guild.ID == "" {
+		cfg.Guild.ID = "1"
+	}
+	if cfg.Guild.Name == "" {
+		cfg.Guild.Name = "console"
+	}
+
+	return &cfg, nil
+}
+
+// RunConsole runs commands read line by line from r as if they were
+// sent to the bot in Discord, without connecting to Discord.
+// Replies are written to w. The user, channel, and guild the
+// commands appear to come from are set by the "console" config key.
+// RunConsole returns when r is exhausted.
+func (b *Bot) RunConsole(r io.Reader, w io.Writer) error {
+	b.logDiscordgo()
+
+	cfg, err := b.loadConsoleCfg()
+	if err != nil {
+		return err
+	}
+	b.start()
+
+	self := &dg.User{ID: "0", Username: "stoopid", Bot: true}
+	user := &dg.User{ID: cfg.User.ID, Username: cfg.User.Name}
+	s := &consoleSession{
+		w:     w,
+		guild: &dg.Guild{ID: cfg.Guild.ID, Name: cfg.Guild.Name},
+		channel: &dg.Channel{
+			ID:      cfg.Channel.ID,
+			GuildID: cfg.Guild.ID,
+			Name:    cfg.Channel.Name,
+			Type:    dg.ChannelTypeGuildText,
+		},
+		members: map[string]*dg.Member{
+			self.ID: {GuildID: cfg.Guild.ID, User: self},
+			user.ID: {GuildID: cfg.Guild.ID, User: user},
+		},
+	}
+
+	sc := bufio.NewScanner(r)
+	for id := 1; sc.Scan(); id++ {
+		b.Dispatch(s, self.ID, &dg.Message{
+			ID:        strconv.Itoa(id),
+			ChannelID: s.channel.ID,
+			GuildID:   s.channel.GuildID,
+			Content:   sc.Text(),
+			Timestamp: time.Now(),
+			Author:    user,
+		})
+	}
+	return sc.Err()
+}
+
+// consoleSession is a Session with a single
+// channel that writes messages to w.
+type consoleSession struct {
+	mu      sync.Mutex
+	w       io.Writer
+	nextID  int
+	guild   *dg.Guild
+	channel *dg.Channel
+	members map[string]*dg.Member
+}
+
+// PlainText implements TextSession, since the console has no limit
+// on the length of text.
+func (s *consoleSession) PlainText() {}
+
+func (s *consoleSession) ChannelMessageSend(channelID, content string) (*dg.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &dg.MessageSend{Content: content})
+}
+
+func (s *consoleSession) ChannelMessageSendEmbed(channelID string, embed *dg.MessageEmbed) (*dg.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &dg.MessageSend{Embeds: []*dg.MessageEmbed{embed}})
+}
+
+func (s *consoleSession) ChannelMessageSendComplex(channelID string, data *dg.MessageSend) (*dg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if data.Content != "" {
+		fmt.Fprintln(s.w, data.Content)
+	}
+	for _, e := range data.Embeds {
+		writeEmbed(s.w, e)
+	}
+	for _, f := range data.Files {
+		fmt.Fprintf(s.w, "[attachment: %s]\n", f.Name)
+	}
+
+	s.nextID++
+	msg := &dg.Message{
+		ID:        "r" + strconv.Itoa(s.nextID),
+		ChannelID: channelID,
+		Content:   data.Content,
+		Embeds:    data.Embeds,
+	}
+	return msg, nil
+}
+
+func (s *consoleSession) ChannelMessageEditComplex(m *dg.MessageEdit) (*dg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := &dg.Message{ID: m.ID, ChannelID: m.Channel}
+	fmt.Fprintf(s.w, "* message %s edited\n", m.ID)
+	if m.Content != nil {
+		msg.Content = *m.Content
+		if msg.Content != "" {
+			fmt.Fprintln(s.w, msg.Content)
+		}
+	}
+	if m.Embeds != nil {
+		msg.Embeds = *m.Embeds
+		for _, e := range msg.Embeds {
+			writeEmbed(s.w, e)
+		}
+	}
+	return msg, nil
+}
+
+func (s *consoleSession) ChannelMessageDelete(channelID, messageID string) error {
+	return nil
+}
+
+func (s *consoleSession) MessageReactionAdd(channelID, messageID, emojiID string) error {
+	s.print("* reacted to message %s with %s", messageID, emojiID)
+	return nil
+}
+
+func (s *consoleSession) MessageReactionsRemoveAll(channelID, messageID string) error {
+	s.print("* reactions removed from message %s", messageID)
+	return nil
+}
+
+func (s *consoleSession) Channel(channelID string) (*dg.Channel, error) {
+	if channelID != s.channel.ID {
+		return nil, dg.ErrStateNotFound
+	}
+	return s.channel, nil
+}
+
+func (s *consoleSession) Guild(guildID string) (*dg.Guild, error) {
+	if guildID != s.guild.ID {
+		return nil, dg.ErrStateNotFound
+	}
+	return s.guild, nil
+}
+
+func (s *consoleSession) GuildMember(guildID, userID string) (*dg.Member, error) {
+	mem, ok := s.members[userID]
+	if !ok || guildID != s.channel.GuildID {
+		return nil, dg.ErrStateNotFound
+	}
+	return mem, nil
+}
+
+func (s *consoleSession) GuildMemberNickname(guildID, userID, nickname string) error {
+	s.print("* nickname changed to %q", nickname)
+	return nil
+}
+
+func (s *consoleSession) UserChannelPermissions(userID, channelID string) (int64, error) {
+	return dg.PermissionAll, nil
+}
+
+func (s *consoleSession) SetAvatar(avatar string) error {
+	s.print("* avatar changed")
+	return nil
+}
+
+func (s *consoleSession) SetStatus(game string) error {
+	s.print("* status changed to %q", game)
+	return nil
+}
+
+func (s *consoleSession) print(format string, v ...interface{}) {
+	s.mu.Lock()
+	fmt.Fprintf(s.w, format+"\n", v...)
+	s.mu.Unlock()
+}
+
+// writeEmbed writes a plain text rendering of an embed to w.
+func writeEmbed(w io.Writer, e *dg.MessageEmbed) {
+	fmt.Fprintln(w, "┌")
+	for _, line := range EmbedText(e) {
+		fmt.Fprintln(w, "│ "+line)
+	}
+	fmt.Fprintln(w, "└")
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CommandDoc describes a command for reference documentation.
+type CommandDoc struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Comment     string   `json:"comment"`
+	Usage       []string `json:"usage"`
+	Description string   `json:"description"`
+	Owner       bool     `json:"owner"`
+	Hidden      bool     `json:"hidden"`
+	Plugin      string   `json:"plugin,omitempty"`
+	Category    string   `json:"category"`
+}
+
+// CommandDocs describes every command, including hidden ones, sorted by
+// category and then name. Aliases are listed with the command they alias.
+func (b *Bot) CommandDocs() []CommandDoc {
+	b.commandsMu.RLock()
+	defer b.commandsMu.RUnlock()
+
+	aliases := make(map[string][]string)
+	for name, cmd := range b.commands {
+		if of := CommandAliasOf(cmd); of != "" && b.commands[of] != nil {
+			aliases[of] = append(aliases[of], name)
+		}
+	}
+
+	var docs []CommandDoc
+	for name, cmd := range b.commands {
+		if of := CommandAliasOf(cmd); of != "" && b.commands[of] != nil {
+			continue
+		}
+		sort.Strings(aliases[name])
+		docs = append(docs, CommandDoc{
+			Name:        name,
+			Aliases:     aliases[name],
+			Comment:     cmd.Comment(),
+			Usage:       cmd.Usage(),
+			Description: cmd.Description(),
+			Owner:       IsOwnerCommand(cmd),
+			Hidden:      IsHiddenCommand(cmd),
+			Plugin:      b.commandPlugins[name],
+			Category:    b.commandCategory(name, cmd),
+		})
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Category != docs[j].Category {
+			return docs[i].Category < docs[j].Category
+		}
+		return docs[i].Name < docs[j].Name
+	})
+	return docs
+}
+
+// WriteDocsJSON writes the sigil and the command docs as JSON.
+func (b *Bot) WriteDocsJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(struct {
+		Sigil    string       `json:"sigil"`
+		Commands []CommandDoc `json:"commands"`
+	}{b.sigil, b.CommandDocs()})
+}
+
+// WriteDocsMarkdown writes the command docs as a Markdown
+// document with a section for each category.
+func (b *Bot) WriteDocsMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	code := func(s string) string { return "`" + b.sigil + s + "`" }
+
+	fmt.Fprintln(bw, "# Commands")
+	category := ""
+	for _, doc := range b.CommandDocs() {
+		if doc.Category != category {
+			category = doc.Category
+			fmt.Fprintf(bw, "\n## %s\n", category)
+		}
+
+		fmt.Fprintf(bw, "\n### %s\n\n", code(doc.Name))
+		if doc.Comment != "" {
+			fmt.Fprintf(bw, "%s\n\n", doc.Comment)
+		}
+
+		var notes []string
+		if doc.Owner {
+			notes = append(notes, "owner only")
+		}
+		if doc.Hidden {
+			notes = append(notes, "hidden from help")
+		}
+		if doc.Plugin != "" {
+			notes = append(notes, "plugin: "+doc.Plugin)
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(bw, "*%s*\n\n", strings.Join(notes, ", "))
+		}
+
+		if len(doc.Usage) > 0 {
+			fmt.Fprintln(bw, "Usage:")
+			fmt.Fprintln(bw)
+			for _, u := range doc.Usage {
+				fmt.Fprintf(bw, "- %s\n", code(u))
+			}
+			fmt.Fprintln(bw)
+		}
+		if len(doc.Aliases) > 0 {
+			aliases := make([]string, len(doc.Aliases))
+			for i, a := range doc.Aliases {
+				aliases[i] = code(a)
+			}
+			fmt.Fprintf(bw, "Aliases: %s\n\n", strings.Join(aliases, ", "))
+		}
+		if doc.Description != "" {
+			fmt.Fprintf(bw, "%s\n", doc.Description)
+		}
+	}
+	return bw.Flush()
+}
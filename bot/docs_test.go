@@ -0,0 +1,38 @@
+package bot_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot"
+)
+
+func TestCommandDocs(t *testing.T) {
+	b := newHelpBot(t)
+	roll := b.GetCommand("roll")
+	b.AddCommand(bot.ToHiddenCommand(bot.ToAliasCommand(bot.SimpleCommand("\U0001F3B2", roll.Execute, bot.SimpleCommandInfo{}), "roll")))
+
+	want := []bot.CommandDoc{
+		{Name: "roll", Aliases: []string{"\U0001F3B2"}, Comment: "roll dice", Usage: []string{"roll d<sides>"}, Description: "Roll dice.", Plugin: "dice", Category: "dice"},
+		{Name: "audit", Comment: "show the audit log", Owner: true, Category: "general"},
+		{Name: "help", Comment: "get info about commands", Category: "general"},
+		{Name: "ignore", Comment: "ignore users, roles, channels, bots or webhooks", Owner: true, Category: "general"},
+		{Name: "jobs", Comment: "list and run scheduled jobs", Owner: true, Category: "general"},
+		{Name: "pool", Comment: "show command queue statistics", Owner: true, Category: "general"},
+		{Name: "say", Comment: "say a message", Owner: true, Category: "general"},
+		{Name: "secret", Comment: "hidden", Hidden: true, Category: "general"},
+		{Name: "shards", Comment: "show shard status", Owner: true, Category: "general"},
+	}
+
+	// only the comments of the bot's own commands are checked
+	builtin := map[string]bool{"audit": true, "help": true, "ignore": true, "jobs": true, "pool": true, "shards": true}
+	docs := b.CommandDocs()
+	for i := range docs {
+		if builtin[docs[i].Name] {
+			docs[i].Usage, docs[i].Description = nil, ""
+		}
+	}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("got %+v, want %+v", docs, want)
+	}
+}
@@ -0,0 +1,232 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// embedConfig is read from the "embeds" config key.
+type embedConfig struct {
+	Color     string // like #5865f2, for embeds that do not set their own
+	Footer    string // for embeds that do not set their own
+	Timestamp bool   // stamp embeds with the time they are built
+}
+
+// embedTheme is how embeds look unless they say otherwise.
+type embedTheme struct {
+	color     int
+	footer    string
+	timestamp bool
+}
+
+// pageFooterRoom is kept free in the total length of each page
+// for the page number added to its footer.
+const pageFooterRoom = 32
+
+func (b *Bot) loadEmbedCfg() error {
+	var cfg embedConfig
+	if b.Config.Exists("embeds") {
+		err := b.Config.Get("embeds", &cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.embedTheme = embedTheme{footer: cfg.Footer, timestamp: cfg.Timestamp}
+	if cfg.Color != "" {
+		color, err := strconv.ParseUint(strings.TrimPrefix(cfg.Color, "#"), 16, 24)
+		if err != nil {
+			return errors.Errorf("embeds: invalid color %q", cfg.Color)
+		}
+		b.embedTheme.color = int(color)
+	}
+	return nil
+}
+
+// EmbedBuilder builds an embed in the bot's theme, keeping it within
+// Discord's limits. Its methods return the builder, so calls can be chained.
+type EmbedBuilder struct {
+	bot     *Bot
+	guildID string
+	embed   dg.MessageEmbed
+}
+
+// NewEmbed starts an embed to be sent in a guild, with the colour, footer
+// and timestamp configured for the bot.
+func (b *Bot) NewEmbed(guildID string) *EmbedBuilder {
+	e := &EmbedBuilder{bot: b, guildID: guildID}
+	e.embed.Color = b.embedTheme.color
+	if b.embedTheme.footer != "" {
+		e.Footer(b.T(guildID, b.embedTheme.footer))
+	}
+	if b.embedTheme.timestamp {
+		e.Timestamp(time.Now())
+	}
+	return e
+}
+
+// Title sets the title of the embed.
+func (e *EmbedBuilder) Title(title string) *EmbedBuilder {
+	e.embed.Title = title
+	return e
+}
+
+// URL sets the link of the embed's title.
+func (e *EmbedBuilder) URL(url string) *EmbedBuilder {
+	e.embed.URL = url
+	return e
+}
+
+// Description sets the text of the embed. Text too long for one embed
+// is split between pages.
+func (e *EmbedBuilder) Description(text string) *EmbedBuilder {
+	e.embed.Description = text
+	return e
+}
+
+// Color sets the colour of the embed, overriding the theme.
+func (e *EmbedBuilder) Color(color int) *EmbedBuilder {
+	e.embed.Color = color
+	return e
+}
+
+// Field adds a field to the embed. Fields that do not fit on one
+// embed are moved to the next page.
+func (e *EmbedBuilder) Field(name, value string, inline bool) *EmbedBuilder {
+	e.embed.Fields = append(e.embed.Fields, &dg.MessageEmbedField{Name: name, Value: value, Inline: inline})
+	return e
+}
+
+// Image sets the URL of the image shown in the embed.
+func (e *EmbedBuilder) Image(url string) *EmbedBuilder {
+	e.embed.Image = &dg.MessageEmbedImage{URL: url}
+	return e
+}
+
+// Thumbnail sets the URL of the thumbnail shown in the embed.
+func (e *EmbedBuilder) Thumbnail(url string) *EmbedBuilder {
+	e.embed.Thumbnail = &dg.MessageEmbedThumbnail{URL: url}
+	return e
+}
+
+// Author sets the name shown as the embed's author.
+func (e *EmbedBuilder) Author(name string) *EmbedBuilder {
+	e.embed.Author = &dg.MessageEmbedAuthor{Name: name}
+	return e
+}
+
+// Footer sets the footer of the embed, overriding the theme.
+// An empty footer removes it.
+func (e *EmbedBuilder) Footer(text string) *EmbedBuilder {
+	e.embed.Footer = nil
+	if text != "" {
+		e.embed.Footer = &dg.MessageEmbedFooter{Text: text}
+	}
+	return e
+}
+
+// FooterNote adds text to the footer of the embed, before the footer it
+// already has, so notes like sources keep the theme's footer after them.
+func (e *EmbedBuilder) FooterNote(text string) *EmbedBuilder {
+	if e.embed.Footer != nil {
+		text += " · " + e.embed.Footer.Text
+	}
+	return e.Footer(text)
+}
+
+// Timestamp sets the time shown in the embed. The zero time removes it.
+func (e *EmbedBuilder) Timestamp(t time.Time) *EmbedBuilder {
+	e.embed.Timestamp = ""
+	if !t.IsZero() {
+		e.embed.Timestamp = t.Format(time.RFC3339)
+	}
+	return e
+}
+
+// Build returns the embed as one page, truncated to fit Discord's limits
+// as for FitEmbed.
+func (e *EmbedBuilder) Build() *dg.MessageEmbed {
+	return FitEmbed(&e.embed)
+}
+
+// Pages returns the embed split between as many pages as it takes to fit
+// Discord's limits. Each page has the title, colour, images and footer of
+// the embed, with the page number added to the footer if there is more
+// than one. The description is split as for SplitText and the fields
+// follow it, moving to a new page when they no longer fit.
+func (e *EmbedBuilder) Pages() []*dg.MessageEmbed {
+	base := e.embed
+	base.Description, base.Fields = "", nil
+	base = *FitEmbed(&base)
+	room := MaxEmbedTotal - EmbedLength(&base) - pageFooterRoom
+
+	var pages []*dg.MessageEmbed
+	newPage := func() *dg.MessageEmbed {
+		page := base
+		pages = append(pages, &page)
+		return &page
+	}
+
+	if e.embed.Description != "" {
+		n := MaxEmbedDesc
+		if room < n {
+			n = room
+		}
+		for _, text := range SplitText(e.embed.Description, n) {
+			newPage().Description = text
+		}
+	}
+
+	var page *dg.MessageEmbed
+	if len(pages) > 0 {
+		page = pages[len(pages)-1]
+	}
+	for _, f := range e.embed.Fields {
+		field := &dg.MessageEmbedField{
+			Name:   truncate(f.Name, MaxEmbedFieldName),
+			Value:  truncate(f.Value, MaxEmbedFieldValue),
+			Inline: f.Inline,
+		}
+		size := utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+		if page == nil || len(page.Fields) == MaxEmbedFields ||
+			EmbedLength(page)-EmbedLength(&base)+size > room {
+			page = newPage()
+		}
+		page.Fields = append(page.Fields, field)
+	}
+
+	if len(pages) == 0 {
+		newPage()
+	}
+	if len(pages) > 1 {
+		for i, page := range pages {
+			footer := e.bot.T(e.guildID, "Page %d/%d", i+1, len(pages))
+			if base.Footer != nil {
+				footer += " · " + base.Footer.Text
+			}
+			page.Footer = &dg.MessageEmbedFooter{Text: footer}
+			pages[i] = FitEmbed(page)
+		}
+	}
+	return pages
+}
+
+// Send replies to m with the embed. If it takes more than one page,
+// they are sent with buttons to turn them, as for Paginate.
+func (e *EmbedBuilder) Send(s Session, m *dg.Message) error {
+	pages := e.Pages()
+	if len(pages) == 1 {
+		return e.bot.Reply(s, m, &dg.MessageSend{Embeds: pages})
+	}
+
+	msgs := make([]*dg.MessageSend, len(pages))
+	for i, page := range pages {
+		msgs[i] = &dg.MessageSend{Embeds: []*dg.MessageEmbed{page}}
+	}
+	return e.bot.Paginate(s, m, msgs)
+}
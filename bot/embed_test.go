@@ -0,0 +1,93 @@
+package bot_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestEmbedTheme(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{
+		"owner": "owner",
+		"sigil": "!",
+		"embeds": map[string]interface{}{
+			"color":     "#5865f2",
+			"footer":    "stoopid",
+			"timestamp": true,
+		},
+	})
+
+	e := b.NewEmbed("").Title("title").Build()
+	if e.Color != 0x5865f2 {
+		t.Errorf("got color %#x, want %#x", e.Color, 0x5865f2)
+	}
+	if e.Footer == nil || e.Footer.Text != "stoopid" {
+		t.Errorf("got footer %+v, want %q", e.Footer, "stoopid")
+	}
+	if _, err := time.Parse(time.RFC3339, e.Timestamp); err != nil {
+		t.Errorf("got timestamp %q: %v", e.Timestamp, err)
+	}
+
+	if e := b.NewEmbed("").FooterNote("source").Build(); e.Footer == nil || e.Footer.Text != "source · stoopid" {
+		t.Errorf("got footer %+v, want the note before the theme's footer", e.Footer)
+	}
+
+	e = b.NewEmbed("").Color(0xff0000).Footer("").Timestamp(time.Time{}).Build()
+	if e.Color != 0xff0000 || e.Footer != nil || e.Timestamp != "" {
+		t.Errorf("theme not overridden: %+v", e)
+	}
+
+	m := bottest.Message("c", "user", "")
+	s := bottest.NewSession()
+	if err := b.NewEmbed("").Title(strings.Repeat("t", 300)).Send(s, m); err != nil {
+		t.Fatal(err)
+	}
+	if embeds := s.Embeds(); len(embeds) != 1 || utf8.RuneCountInString(embeds[0].Title) != bot.MaxEmbedTitle {
+		t.Errorf("got %+v, want one embed with its title truncated", embeds)
+	}
+}
+
+func TestEmbedPages(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+
+	if pages := b.NewEmbed("").Title("short").Field("a", "b", true).Pages(); len(pages) != 1 || pages[0].Footer != nil {
+		t.Errorf("got %d pages, want 1 without a page number", len(pages))
+	}
+
+	line := strings.Repeat("x", 99) + "\n"
+	e := b.NewEmbed("").Title("title").Footer("footer").Description(strings.Repeat(line, 50))
+	for i := 0; i < 30; i++ {
+		e.Field("name", strings.Repeat("v", 500), false)
+	}
+
+	pages := e.Pages()
+	fields := 0
+	for i, p := range pages {
+		if n := bot.EmbedLength(p); n > bot.MaxEmbedTotal {
+			t.Errorf("page %d is %d long", i, n)
+		}
+		if n := utf8.RuneCountInString(p.Description); n > bot.MaxEmbedDesc {
+			t.Errorf("page %d has a description %d long", i, n)
+		}
+		if len(p.Fields) > bot.MaxEmbedFields {
+			t.Errorf("page %d has %d fields", i, len(p.Fields))
+		}
+		if p.Title != "title" {
+			t.Errorf("page %d has title %q", i, p.Title)
+		}
+		if !strings.HasSuffix(p.Footer.Text, " · footer") || !strings.HasPrefix(p.Footer.Text, "Page ") {
+			t.Errorf("page %d has footer %q", i, p.Footer.Text)
+		}
+		fields += len(p.Fields)
+	}
+	if fields != 30 {
+		t.Errorf("got %d fields across %d pages, want 30", fields, len(pages))
+	}
+	if len(pages) < 3 {
+		t.Errorf("got %d pages, want at least 3", len(pages))
+	}
+}
@@ -0,0 +1,276 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+// Names of the events published by the bot.
+// Custom events published with Emit may use any other name.
+const (
+	MessageEventName        = "message"
+	ReactionAddEventName    = "reaction add"
+	ReactionRemoveEventName = "reaction remove"
+	MemberJoinEventName     = "member join"
+	MemberLeaveEventName    = "member leave"
+	GuildJoinEventName      = "guild join"
+	ReadyEventName          = "ready"
+	CommandEventName        = "command"
+)
+
+// MessageEvent is published when a message is sent to a channel the bot
+// can see. The bot's own messages and ignored messages are not published.
+type MessageEvent struct {
+	Session Session
+	Message *dg.Message
+}
+
+// ReactionEvent is published when a reaction is added to or removed
+// from a message. The bot's own reactions and reactions by ignored
+// users are not published.
+type ReactionEvent struct {
+	Session  Session
+	Reaction *dg.MessageReaction
+}
+
+// MemberEvent is published when a member joins or leaves a guild,
+// unless the member is ignored.
+type MemberEvent struct {
+	Session Session
+	Member  *dg.Member
+}
+
+// GuildEvent is published when the bot joins a guild.
+type GuildEvent struct {
+	Session Session
+	Guild   *dg.Guild
+}
+
+// ReadyEvent is published when a shard is ready.
+type ReadyEvent struct {
+	Session Session
+	Ready   *dg.Ready
+}
+
+// CommandEvent is published after a command is run.
+// Message is the invoking message, with the sigil
+// and command name removed from its content.
+type CommandEvent struct {
+	Session Session
+	Name    string
+	Message *dg.Message
+}
+
+// CustomEvent is an event published by a plugin with Emit.
+type CustomEvent struct {
+	Name string
+	Data interface{}
+}
+
+type subscription struct {
+	plugin string
+	fn     func(interface{})
+}
+
+// eventBus delivers events to the functions subscribed to them.
+type eventBus struct {
+	mu   sync.RWMutex
+	subs map[string][]*subscription
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[string][]*subscription)}
+}
+
+// subscribe adds fn to the subscribers to an event. If called while
+// loading a plugin, it is removed when the plugin is unloaded.
+func (b *Bot) subscribe(event string, fn func(interface{})) {
+	b.commandsMu.RLock()
+	sub := &subscription{plugin: b.loading, fn: fn}
+	b.commandsMu.RUnlock()
+
+	b.events.mu.Lock()
+	b.events.subs[event] = append(b.events.subs[event], sub)
+	b.events.mu.Unlock()
+}
+
+// unsubscribe removes the subscriptions of a plugin.
+func (b *Bot) unsubscribe(plugin string) {
+	b.events.mu.Lock()
+	defer b.events.mu.Unlock()
+	for event, subs := range b.events.subs {
+		kept := make([]*subscription, 0, len(subs))
+		for _, sub := range subs {
+			if sub.plugin != plugin {
+				kept = append(kept, sub)
+			}
+		}
+		b.events.subs[event] = kept
+	}
+}
+
+// subscribed reports whether anything is subscribed to an event.
+func (b *Bot) subscribed(event string) bool {
+	b.events.mu.RLock()
+	defer b.events.mu.RUnlock()
+	return len(b.events.subs[event]) > 0
+}
+
+// publish calls the subscribers to an event in the order they
+// subscribed. A subscriber that panics is logged and skipped.
+func (b *Bot) publish(event string, e interface{}) {
+	b.events.mu.RLock()
+	subs := b.events.subs[event]
+	b.events.mu.RUnlock()
+
+	for _, sub := range subs {
+		b.deliver(event, sub, e)
+	}
+}
+
+func (b *Bot) deliver(event string, sub *subscription, e interface{}) {
+	b.safely(fmt.Sprintf("%q handler", event), sub.plugin, func() { sub.fn(e) })
+}
+
+// OnMessage subscribes fn to messages.
+func (b *Bot) OnMessage(fn func(*MessageEvent)) {
+	b.subscribe(MessageEventName, func(e interface{}) { fn(e.(*MessageEvent)) })
+}
+
+// OnReactionAdd subscribes fn to reactions being added.
+func (b *Bot) OnReactionAdd(fn func(*ReactionEvent)) {
+	b.subscribe(ReactionAddEventName, func(e interface{}) { fn(e.(*ReactionEvent)) })
+}
+
+// OnReactionRemove subscribes fn to reactions being removed.
+func (b *Bot) OnReactionRemove(fn func(*ReactionEvent)) {
+	b.subscribe(ReactionRemoveEventName, func(e interface{}) { fn(e.(*ReactionEvent)) })
+}
+
+// OnMemberJoin subscribes fn to members joining guilds.
+// Member events need the privileged server members intent,
+// which is requested when connecting if anything subscribes to them.
+func (b *Bot) OnMemberJoin(fn func(*MemberEvent)) {
+	b.subscribe(MemberJoinEventName, func(e interface{}) { fn(e.(*MemberEvent)) })
+}
+
+// OnMemberLeave subscribes fn to members leaving guilds.
+// See OnMemberJoin for the intent it needs.
+func (b *Bot) OnMemberLeave(fn func(*MemberEvent)) {
+	b.subscribe(MemberLeaveEventName, func(e interface{}) { fn(e.(*MemberEvent)) })
+}
+
+// OnGuildJoin subscribes fn to the bot joining guilds.
+func (b *Bot) OnGuildJoin(fn func(*GuildEvent)) {
+	b.subscribe(GuildJoinEventName, func(e interface{}) { fn(e.(*GuildEvent)) })
+}
+
+// OnReady subscribes fn to shards becoming ready.
+func (b *Bot) OnReady(fn func(*ReadyEvent)) {
+	b.subscribe(ReadyEventName, func(e interface{}) { fn(e.(*ReadyEvent)) })
+}
+
+// OnCommand subscribes fn to commands being run.
+func (b *Bot) OnCommand(fn func(*CommandEvent)) {
+	b.subscribe(CommandEventName, func(e interface{}) { fn(e.(*CommandEvent)) })
+}
+
+// On subscribes fn to a custom event published with Emit.
+func (b *Bot) On(name string, fn func(*CustomEvent)) {
+	b.subscribe(customEventName(name), func(e interface{}) { fn(e.(*CustomEvent)) })
+}
+
+// Emit publishes a custom event, returning once every subscriber has
+// handled it. Plugins should prefix the names of their events with
+// their own name, like "tags.added", to keep them apart.
+func (b *Bot) Emit(name string, data interface{}) {
+	b.publish(customEventName(name), &CustomEvent{Name: name, Data: data})
+}
+
+// customEventName keeps custom events apart from the bot's own.
+func customEventName(name string) string {
+	return "custom:" + name
+}
+
+// The handlers below publish discordgo's events on the bus.
+
+func (b *Bot) publishMessage(s *dg.Session, m *dg.MessageCreate) {
+	if m.Author == nil || m.Author.ID == s.State.User.ID || b.ignoredMessage(m.Message) {
+		return
+	}
+	b.publish(MessageEventName, &MessageEvent{Session: DiscordSession(s), Message: m.Message})
+}
+
+// ignoredReaction reports whether a reaction is
+// the bot's own or made by a user the bot ignores.
+func (b *Bot) ignoredReaction(s *dg.Session, r *dg.MessageReaction, member *dg.Member) bool {
+	if r.UserID == s.State.User.ID {
+		return true
+	}
+	user := &dg.User{ID: r.UserID}
+	var roles []string
+	if member != nil {
+		roles = member.Roles
+		if member.User != nil {
+			user = member.User
+		}
+	}
+	return b.ignored(user, roles, r.ChannelID)
+}
+
+func (b *Bot) publishReactionAdd(s *dg.Session, r *dg.MessageReactionAdd) {
+	if b.ignoredReaction(s, r.MessageReaction, r.Member) {
+		return
+	}
+	b.publish(ReactionAddEventName, &ReactionEvent{Session: DiscordSession(s), Reaction: r.MessageReaction})
+}
+
+func (b *Bot) publishReactionRemove(s *dg.Session, r *dg.MessageReactionRemove) {
+	// unlike additions, removals do not come with the member
+	var member *dg.Member
+	if r.GuildID != "" {
+		var err error
+		member, err = DiscordSession(s).GuildMember(r.GuildID, r.UserID)
+		if err != nil {
+			b.Logf("failed to look up member removing a reaction: %v", err)
+		}
+	}
+	if b.ignoredReaction(s, r.MessageReaction, member) {
+		return
+	}
+	b.publish(ReactionRemoveEventName, &ReactionEvent{Session: DiscordSession(s), Reaction: r.MessageReaction})
+}
+
+func (b *Bot) publishMemberJoin(s *dg.Session, m *dg.GuildMemberAdd) {
+	if b.ignored(m.User, m.Roles, "") {
+		return
+	}
+	b.publish(MemberJoinEventName, &MemberEvent{Session: DiscordSession(s), Member: m.Member})
+}
+
+func (b *Bot) publishMemberLeave(s *dg.Session, m *dg.GuildMemberRemove) {
+	if b.ignored(m.User, m.Roles, "") {
+		return
+	}
+	b.publish(MemberLeaveEventName, &MemberEvent{Session: DiscordSession(s), Member: m.Member})
+}
+
+func (b *Bot) publishReady(s *dg.Session, r *dg.Ready) {
+	b.publish(ReadyEventName, &ReadyEvent{Session: DiscordSession(s), Ready: r})
+}
+
+// joinWindow is how recently the bot must have joined
+// a guild being created for it to count as joining.
+const joinWindow = time.Minute
+
+// publishGuildJoin publishes guilds being created that the bot has just
+// joined. Guilds the bot is already in are also created when connecting.
+func (b *Bot) publishGuildJoin(s *dg.Session, g *dg.GuildCreate) {
+	if g.JoinedAt.IsZero() || time.Since(g.JoinedAt) > joinWindow {
+		return
+	}
+	b.publish(GuildJoinEventName, &GuildEvent{Session: DiscordSession(s), Guild: g.Guild})
+}
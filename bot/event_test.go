@@ -0,0 +1,62 @@
+package bot_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestCustomEvents(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+
+	var got []string
+	err := b.AddPlugin(bot.SimplePlugin("listener", func(b *bot.Bot) error {
+		b.On("tags.added", func(e *bot.CustomEvent) {
+			got = append(got, "listener "+e.Data.(string))
+		})
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.On("tags.added", func(*bot.CustomEvent) { panic("oops") })
+	b.On("tags.added", func(e *bot.CustomEvent) {
+		got = append(got, "after panic "+e.Data.(string))
+	})
+	b.On("tags.removed", func(e *bot.CustomEvent) {
+		got = append(got, "removed "+e.Data.(string))
+	})
+
+	b.Emit("tags.added", "one")
+	err = b.UnloadPlugin("listener")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Emit("tags.added", "two")
+
+	want := []string{"listener one", "after panic one", "after panic two"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCommandEvents(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	b.AddCommand(bot.SimpleCommand("roll", nop, bot.SimpleCommandInfo{}))
+
+	var got []string
+	b.OnCommand(func(e *bot.CommandEvent) {
+		got = append(got, e.Name+" "+e.Message.Content)
+	})
+
+	s := bottest.NewSession()
+	b.Dispatch(s, "self", bottest.Message("c", "user", "!roll d6"))
+	b.Dispatch(s, "self", bottest.Message("c", "user", "!missing"))
+	b.Dispatch(s, "self", bottest.Message("c", "user", "not a command"))
+
+	if want := []string{"roll d6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
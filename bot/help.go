@@ -0,0 +1,346 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+const (
+	defaultCategory = "general"
+	helpPageSize    = 10
+	maxComment      = 60
+)
+
+type helpCommand struct {
+	*Bot
+}
+
+func (c helpCommand) Name() string {
+	return "help"
+}
+
+func (c helpCommand) Comment() string {
+	return "get info about commands"
+}
+
+func (c helpCommand) Usage() []string {
+	return []string{"help [<page>]", "help <command>", "help search <text>"}
+}
+
+func (c helpCommand) Description() string {
+	return "Get information about commands. If a command is not specified, list all commands by category. Use search to list the commands mentioning some text."
+}
+
+func (c helpCommand) Arguments() []Argument {
+	return []Argument{{
+		Name:        "command",
+		Description: "the command to get info about",
+		Complete:    c.complete,
+	}}
+}
+
+// complete suggests the names of visible commands.
+func (c helpCommand) complete(partial string) []string {
+	c.commandsMu.RLock()
+	defer c.commandsMu.RUnlock()
+
+	var names []string
+	for name, cmd := range c.commands {
+		if !IsHiddenCommand(cmd) && strings.HasPrefix(name, partial) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c helpCommand) Execute(s Session, m *dg.Message) {
+	var err error
+	switch {
+	case m.Content == "":
+		err = c.helplist(s, m, "", 0)
+	case strings.HasPrefix(m.Content, "search "):
+		err = c.helplist(s, m, strings.TrimSpace(m.Content[len("search "):]), 0)
+	default:
+		page, perr := strconv.Atoi(m.Content)
+		if perr == nil && c.GetCommand(m.Content) == nil {
+			err = c.helplist(s, m, "", page-1)
+		} else {
+			err = c.help(s, m)
+		}
+	}
+	if err != nil {
+		c.Log("[help]", err)
+	}
+}
+
+const missingText = "<undefined>"
+
+func (c helpCommand) help(s Session, m *dg.Message) error {
+	cmd := c.GetCommand(m.Content)
+	if cmd == nil {
+		cmd, _ = c.sourceCommand(m.GuildID, m.Content)
+	}
+	if cmd == nil {
+		_, err := s.ChannelMessageSend(m.ChannelID, c.T(m.GuildID, "Command not found."))
+		return err
+	}
+
+	ownercmd := IsOwnerCommand(cmd)
+	if ownercmd && m.Author.ID != c.owner {
+		_, err := s.ChannelMessageSend(m.ChannelID, c.T(m.GuildID, "You do not have permission to use that command."))
+		return err
+	}
+
+	usages := cmd.Usage()
+	for i, s := range usages {
+		usages[i] = "`" + c.sigil + s + "`"
+	}
+	usage := strings.Join(usages, "\n")
+	if usage == "" {
+		usage = missingText
+	}
+
+	description := c.T(m.GuildID, cmd.Description())
+	if description == "" {
+		description = missingText
+	}
+	if ownercmd {
+		description += "\n\n" + c.T(m.GuildID, "Owner only.")
+	}
+
+	usageName := c.T(m.GuildID, "Usage:")
+	descriptionName := c.T(m.GuildID, "Description:")
+	footer := c.T(m.GuildID, "For a list of all commands, use %shelp", c.sigil)
+
+	if !canEmbed(s, m.ChannelID) {
+		text := fmt.Sprintf("**%s**\n%s\n**%s**\n%s\n*%s*", usageName, usage, descriptionName, description, footer)
+		_, err := s.ChannelMessageSend(m.ChannelID, text)
+		return err
+	}
+
+	return c.NewEmbed(m.GuildID).
+		Field(usageName, usage, false).
+		Field(descriptionName, description, false).
+		FooterNote(footer).
+		Send(s, m)
+}
+
+// canEmbed reports whether the bot may send embeds in a channel.
+// If its permissions cannot be found, it is assumed that it may.
+func canEmbed(s Session, channelID string) bool {
+	perms, err := s.UserChannelPermissions("@me", channelID)
+	return err != nil || perms&dg.PermissionEmbedLinks != 0
+}
+
+func (c helpCommand) helplist(s Session, m *dg.Message, query string, page int) error {
+	entries := c.entries(m.GuildID, m.Author.ID, query)
+	if len(entries) == 0 {
+		_, err := s.ChannelMessageSend(m.ChannelID, c.T(m.GuildID, "No commands found."))
+		return err
+	}
+
+	data := c.page(entries, m.GuildID, m.Author.ID, query, page, canEmbed(s, m.ChannelID))
+	_, err := s.ChannelMessageSendComplex(m.ChannelID, data)
+	return err
+}
+
+// helpEntry is a command as listed in help.
+type helpEntry struct {
+	category, name, comment string
+	owner                   bool
+	source                  int // 1 + the index of the command's source, if any
+}
+
+// entries returns the commands visible to a user in a guild that mention
+// query, sorted by category and then name, with the commands of each
+// command source last.
+func (c helpCommand) entries(guildID, userID, query string) []helpEntry {
+	c.commandsMu.RLock()
+	defer c.commandsMu.RUnlock()
+
+	query = strings.ToLower(query)
+
+	entries := make([]helpEntry, 0, len(c.commands))
+	add := func(name string, cmd Command, category string, source int) {
+		ownercmd := IsOwnerCommand(cmd)
+		if IsHiddenCommand(cmd) || (ownercmd && userID != c.owner) {
+			return
+		}
+
+		e := helpEntry{
+			category: category,
+			name:     name,
+			comment:  cmd.Comment(),
+			owner:    ownercmd,
+			source:   source,
+		}
+		if query != "" && !matches(query, e.category, name, e.comment, cmd.Description()) {
+			return
+		}
+		if e.name == "" {
+			e.name = missingText
+		}
+		if e.comment == "" {
+			e.comment = missingText
+		}
+		if r := []rune(e.comment); len(r) > maxComment {
+			e.comment = string(r[:maxComment-1]) + "…"
+		}
+		entries = append(entries, e)
+	}
+	for name, cmd := range c.commands {
+		add(name, cmd, c.commandCategory(name, cmd), 0)
+	}
+	for i, src := range c.sources {
+		for _, cmd := range src.Commands(guildID) {
+			add(cmd.Name(), cmd, src.Name(), i+1)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].source != entries[j].source {
+			return entries[i].source < entries[j].source
+		}
+		if entries[i].category != entries[j].category {
+			return entries[i].category < entries[j].category
+		}
+		return entries[i].name < entries[j].name
+	})
+	return entries
+}
+
+func matches(query string, texts ...string) bool {
+	for _, text := range texts {
+		if strings.Contains(strings.ToLower(text), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// page builds one page of a list of commands, as an embed or as plain
+// text, with buttons to turn the page if there is more than one.
+func (c helpCommand) page(entries []helpEntry, guildID, userID, query string, page int, embed bool) *dg.MessageSend {
+	pages := (len(entries) + helpPageSize - 1) / helpPageSize
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	entries = entries[page*helpPageSize:]
+	if len(entries) > helpPageSize {
+		entries = entries[:helpPageSize]
+	}
+
+	title := c.T(guildID, "Commands:")
+	if query != "" {
+		title = c.T(guildID, "Commands matching %q:", query)
+	}
+	footer := c.T(guildID, "For more information, use %shelp <command>", c.sigil)
+	if pages > 1 {
+		footer = c.T(guildID, "Page %d/%d", page+1, pages) + " · " + footer
+	}
+
+	// group the page's commands by category and source
+	var fields []*dg.MessageEmbedField
+	for i, e := range entries {
+		line := "`" + c.sigil + e.name + "` " + c.T(guildID, e.comment)
+		if e.owner {
+			line += " " + c.T(guildID, "(owner only)")
+		}
+		if prev := i - 1; prev >= 0 && entries[prev].category == e.category && entries[prev].source == e.source {
+			fields[len(fields)-1].Value += "\n" + line
+		} else {
+			fields = append(fields, &dg.MessageEmbedField{Name: c.T(guildID, e.category), Value: line})
+		}
+	}
+
+	data := new(dg.MessageSend)
+	if embed {
+		embed := c.NewEmbed(guildID).Title(title).FooterNote(footer)
+		for _, f := range fields {
+			embed.Field(f.Name, f.Value, false)
+		}
+		data.Embeds = []*dg.MessageEmbed{embed.Build()}
+	} else {
+		lines := []string{"**" + title + "**"}
+		for _, f := range fields {
+			lines = append(lines, "__"+f.Name+"__", f.Value)
+		}
+		lines = append(lines, "*"+footer+"*")
+		data.Content = strings.Join(lines, "\n")
+	}
+
+	if pages > 1 {
+		button := func(label string, to int) dg.Button {
+			return dg.Button{
+				Label:    label,
+				Style:    dg.SecondaryButton,
+				CustomID: helpCustomID(userID, to, query),
+				Disabled: to < 0 || to >= pages,
+			}
+		}
+		data.Components = []dg.MessageComponent{dg.ActionsRow{Components: []dg.MessageComponent{
+			button("◀", page-1),
+			button("▶", page+1),
+		}}}
+	}
+	return data
+}
+
+// maxCustomID is the longest custom ID Discord accepts for a component.
+const maxCustomID = 100
+
+// helpCustomID identifies a button turning to a page of help for a user.
+// It holds everything needed to build the page, so buttons keep
+// working after a restart. A query too long to fit is cut short
+// between runes.
+func helpCustomID(userID string, page int, query string) string {
+	id := "help:" + userID + ":" + strconv.Itoa(page) + ":" + query
+	if len(id) > maxCustomID {
+		n := maxCustomID
+		for n > 0 && !utf8.RuneStart(id[n]) {
+			n--
+		}
+		id = id[:n]
+	}
+	return id
+}
+
+// component turns the page of a list of commands.
+func (c helpCommand) component(s *dg.Session, i *dg.Interaction) {
+	parts := strings.SplitN(i.MessageComponentData().CustomID, ":", 4)
+	if len(parts) != 4 {
+		return
+	}
+	userID, query := parts[1], parts[3]
+	page, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return
+	}
+
+	if user := interactionUser(i); user == nil || user.ID != userID {
+		c.respondEphemeral(s, i, c.T(i.GuildID, "Only the person who asked for help can turn its pages."))
+		return
+	}
+
+	data := c.page(c.entries(i.GuildID, userID, query), i.GuildID, userID, query, page, canEmbed(DiscordSession(s), i.ChannelID))
+	err = s.InteractionRespond(i, &dg.InteractionResponse{
+		Type: dg.InteractionResponseUpdateMessage,
+		Data: &dg.InteractionResponseData{
+			Content:    data.Content,
+			Embeds:     data.Embeds,
+			Components: data.Components,
+		},
+	})
+	if err != nil {
+		c.Log("[help]", err)
+	}
+}
@@ -0,0 +1,22 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHelpCustomID(t *testing.T) {
+	for _, query := range []string{"", "roll", strings.Repeat("é", 60), strings.Repeat("x", 79) + "日本"} {
+		id := helpCustomID("123456789", 2, query)
+		if len(id) > maxCustomID {
+			t.Errorf("%q: custom ID %d bytes long", query, len(id))
+		}
+		if !utf8.ValidString(id) {
+			t.Errorf("%q: custom ID %q is not valid UTF-8", query, id)
+		}
+		if prefix := "help:123456789:2:"; !strings.HasPrefix(id, prefix) || !strings.HasPrefix(query, id[len(prefix):]) {
+			t.Errorf("%q: got custom ID %q", query, id)
+		}
+	}
+}
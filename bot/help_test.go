@@ -0,0 +1,168 @@
+package bot_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func nop(bot.Session, *dg.Message) {}
+
+func newHelpBot(t *testing.T) *bot.Bot {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	err := b.AddPlugin(bot.SimplePlugin("dice", func(b *bot.Bot) error {
+		b.AddCommand(bot.SimpleCommand("roll", nop, bot.SimpleCommandInfo{
+			Comment:     "roll dice",
+			Usage:       []string{"roll d<sides>"},
+			Description: "Roll dice.",
+		}))
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddCommand(bot.ToOwnerCommand(bot.SimpleCommand("say", nop, bot.SimpleCommandInfo{
+		Comment: "say a message",
+	})))
+	b.AddCommand(bot.ToHiddenCommand(bot.SimpleCommand("secret", nop, bot.SimpleCommandInfo{
+		Comment: "hidden",
+	})))
+	return b
+}
+
+func fields(fields []*dg.MessageEmbedField) []string {
+	s := make([]string, len(fields))
+	for i, f := range fields {
+		s[i] = f.Name + ": " + f.Value
+	}
+	return s
+}
+
+func TestHelpList(t *testing.T) {
+	tests := []struct {
+		author  string
+		content string
+		fields  []string
+	}{
+		{"user", "", []string{
+			"dice: `!roll` roll dice",
+			"general: `!help` get info about commands",
+		}},
+		{"owner", "", []string{
+			"dice: `!roll` roll dice",
+			"general: `!audit` show the audit log (owner only)\n`!help` get info about commands\n" +
+				"`!ignore` ignore users, roles, channels, bots or webhooks (owner only)\n`!jobs` list and run scheduled jobs (owner only)\n" +
+				"`!pool` show command queue statistics (owner only)\n`!say` say a message (owner only)\n`!shards` show shard status (owner only)",
+		}},
+		{"user", "search DICE", []string{"dice: `!roll` roll dice"}},
+		{"user", "search message", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.author+" "+tt.content, func(t *testing.T) {
+			b := newHelpBot(t)
+			s := bottest.NewSession()
+			b.GetCommand("help").Execute(s, bottest.Message("c", tt.author, tt.content))
+
+			if tt.fields == nil {
+				if got := s.Contents(); !reflect.DeepEqual(got, []string{"No commands found."}) {
+					t.Errorf("got %q, want no commands found", got)
+				}
+				return
+			}
+			embeds := s.Embeds()
+			if len(embeds) != 1 {
+				t.Fatalf("got %d embeds, want 1", len(embeds))
+			}
+			if got := fields(embeds[0].Fields); !reflect.DeepEqual(got, tt.fields) {
+				t.Errorf("got fields %q, want %q", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestHelpPages(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	for i := 0; i < 25; i++ {
+		b.AddCommand(bot.SimpleCommand(fmt.Sprintf("cmd%02d", i), nop, bot.SimpleCommandInfo{Comment: "a command"}))
+	}
+
+	s := bottest.NewSession()
+	b.GetCommand("help").Execute(s, bottest.Message("c", "user", "3"))
+
+	embeds := s.Embeds()
+	if len(embeds) != 1 {
+		t.Fatalf("got %d embeds, want 1", len(embeds))
+	}
+	if got := embeds[0].Fields[0].Value; strings.Count(got, "\n") != 5 || !strings.HasPrefix(got, "`!cmd20`") {
+		t.Errorf("got last page %q, want cmd20 to cmd24 and help", got)
+	}
+	if got := embeds[0].Footer.Text; !strings.HasPrefix(got, "Page 3/3") {
+		t.Errorf("got footer %q, want page 3/3", got)
+	}
+	if len(s.Sent[0].Components) != 1 {
+		t.Errorf("got %d components, want a row of buttons", len(s.Sent[0].Components))
+	}
+}
+
+func TestHelpWithoutEmbeds(t *testing.T) {
+	b := newHelpBot(t)
+	s := bottest.NewSession()
+	s.Permissions = map[string]int64{"c": dg.PermissionSendMessages}
+
+	b.GetCommand("help").Execute(s, bottest.Message("c", "user", ""))
+	b.GetCommand("help").Execute(s, bottest.Message("c", "user", "roll"))
+
+	if embeds := s.Embeds(); len(embeds) != 0 {
+		t.Fatalf("sent %d embeds without permission", len(embeds))
+	}
+	contents := s.Contents()
+	if len(contents) != 2 || !strings.Contains(contents[0], "`!roll` roll dice") || !strings.Contains(contents[1], "`!roll d<sides>`") {
+		t.Errorf("got %q, want commands and usage as text", contents)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	tests := []struct {
+		name    string
+		author  string
+		content string
+		text    string
+		usage   string
+	}{
+		{"command", "user", "roll", "", "`!roll d<sides>`"},
+		{"missing info", "owner", "say", "", "<undefined>"},
+		{"not found", "user", "nope", "Command not found.", ""},
+		{"owner only", "user", "say", "You do not have permission to use that command.", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newHelpBot(t)
+			s := bottest.NewSession()
+			b.GetCommand("help").Execute(s, bottest.Message("c", tt.author, tt.content))
+
+			if len(s.Sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(s.Sent))
+			}
+			m := s.Sent[0]
+			if m.Content != tt.text {
+				t.Errorf("got text %q, want %q", m.Content, tt.text)
+			}
+			if tt.usage == "" {
+				return
+			}
+			if len(m.Embeds) != 1 || len(m.Embeds[0].Fields) == 0 {
+				t.Fatalf("got %v, want an embed with fields", m.Embeds)
+			}
+			if got := m.Embeds[0].Fields[0].Value; got != tt.usage {
+				t.Errorf("got usage %q, want %q", got, tt.usage)
+			}
+		})
+	}
+}
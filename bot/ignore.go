@@ -0,0 +1,288 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// Kinds of things the bot can ignore.
+const (
+	IgnoreUser    = "user"
+	IgnoreRole    = "role"
+	IgnoreChannel = "channel"
+)
+
+var ignoreKinds = []string{IgnoreUser, IgnoreRole, IgnoreChannel}
+
+// ignoreConfig is read from the "ignore" config key.
+// Bots and webhooks are ignored unless it says otherwise.
+type ignoreConfig struct {
+	Users    []string
+	Roles    []string
+	Channels []string
+	Bots     *bool
+	Webhooks *bool
+}
+
+// Switches for ignoring every bot or webhook, as
+// accepted by the ignore command and stored.
+const (
+	ignoreBots     = "bots"
+	ignoreWebhooks = "webhooks"
+)
+
+// ignoreLists holds what the config says to ignore.
+type ignoreLists struct {
+	ids            map[string]map[string]bool // by kind, then ID
+	bots, webhooks bool
+}
+
+const ignorePrefix = "ignore/"
+
+func (b *Bot) loadIgnoreCfg() error {
+	var cfg ignoreConfig
+	if b.Config.Exists("ignore") {
+		err := b.Config.Get("ignore", &cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.ignore = ignoreLists{
+		ids:      make(map[string]map[string]bool),
+		bots:     cfg.Bots == nil || *cfg.Bots,
+		webhooks: cfg.Webhooks == nil || *cfg.Webhooks,
+	}
+	for kind, ids := range map[string][]string{
+		IgnoreUser:    cfg.Users,
+		IgnoreRole:    cfg.Roles,
+		IgnoreChannel: cfg.Channels,
+	} {
+		b.ignore.ids[kind] = make(map[string]bool)
+		for _, id := range ids {
+			b.ignore.ids[kind][id] = true
+		}
+	}
+	return nil
+}
+
+func ignoreKey(kind, id string) string {
+	return ignorePrefix + kind + "/" + id
+}
+
+func validIgnoreKind(kind string) bool {
+	for _, k := range ignoreKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Ignoring reports whether the bot ignores the user, role or
+// channel with an ID, as set by the config or SetIgnoring.
+func (b *Bot) Ignoring(kind, id string) bool {
+	var ignoring bool
+	if b.Store.Get(ignoreKey(kind, id), &ignoring) == nil {
+		return ignoring
+	}
+	return b.ignore.ids[kind][id]
+}
+
+// SetIgnoring sets whether the bot ignores the user, role or channel
+// with an ID, overriding the config. The change is persisted.
+func (b *Bot) SetIgnoring(kind, id string, ignoring bool) error {
+	if !validIgnoreKind(kind) {
+		return errors.Errorf("cannot ignore a %q", kind)
+	}
+	if id == "" {
+		return errors.Errorf("no %s to ignore", kind)
+	}
+	return b.Store.Set(ignoreKey(kind, id), ignoring)
+}
+
+func (b *Bot) ignoringSwitch(name string, def bool) bool {
+	var ignoring bool
+	if b.Store.Get(ignorePrefix+name, &ignoring) == nil {
+		return ignoring
+	}
+	return def
+}
+
+// IgnoringBots reports whether the bot ignores other bots,
+// as set by the config or SetIgnoringBots.
+func (b *Bot) IgnoringBots() bool {
+	return b.ignoringSwitch(ignoreBots, b.ignore.bots)
+}
+
+// SetIgnoringBots sets whether the bot ignores other bots,
+// overriding the config. The change is persisted.
+func (b *Bot) SetIgnoringBots(ignoring bool) error {
+	return b.Store.Set(ignorePrefix+ignoreBots, ignoring)
+}
+
+// IgnoringWebhooks reports whether the bot ignores messages from
+// webhooks, as set by the config or SetIgnoringWebhooks.
+func (b *Bot) IgnoringWebhooks() bool {
+	return b.ignoringSwitch(ignoreWebhooks, b.ignore.webhooks)
+}
+
+// SetIgnoringWebhooks sets whether the bot ignores messages from
+// webhooks, overriding the config. The change is persisted.
+func (b *Bot) SetIgnoringWebhooks(ignoring bool) error {
+	return b.Store.Set(ignorePrefix+ignoreWebhooks, ignoring)
+}
+
+// IgnoreList returns the IDs of the things of a kind the bot ignores.
+func (b *Bot) IgnoreList(kind string) []string {
+	set := make(map[string]bool)
+	for id := range b.ignore.ids[kind] {
+		set[id] = true
+	}
+	prefix := ignorePrefix + kind + "/"
+	for _, key := range b.Store.Keys(prefix) {
+		id := key[len(prefix):]
+		set[id] = b.Ignoring(kind, id)
+	}
+
+	var ids []string
+	for id, ignoring := range set {
+		if ignoring {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ignored reports whether the bot ignores a user, who has the
+// given roles, in a channel. The owner is never ignored.
+func (b *Bot) ignored(user *dg.User, roles []string, channelID string) bool {
+	if user == nil {
+		return false
+	}
+	if user.ID == b.owner {
+		return false
+	}
+	if user.Bot && b.IgnoringBots() {
+		return true
+	}
+	if b.Ignoring(IgnoreUser, user.ID) || b.Ignoring(IgnoreChannel, channelID) {
+		return true
+	}
+	for _, role := range roles {
+		if b.Ignoring(IgnoreRole, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredMessage reports whether the bot ignores a message.
+func (b *Bot) ignoredMessage(m *dg.Message) bool {
+	if m.WebhookID != "" && b.IgnoringWebhooks() {
+		return true
+	}
+	var roles []string
+	if m.Member != nil {
+		roles = m.Member.Roles
+	}
+	return b.ignored(m.Author, roles, m.ChannelID)
+}
+
+type ignoreCommand struct {
+	*Bot
+}
+
+func (c ignoreCommand) Name() string {
+	return "ignore"
+}
+
+func (c ignoreCommand) Comment() string {
+	return "ignore users, roles, channels, bots or webhooks"
+}
+
+func (c ignoreCommand) Usage() []string {
+	return []string{"ignore", "ignore <user|role|channel> <id>", "ignore remove <user|role|channel> <id>", "ignore [remove] <bots|webhooks>"}
+}
+
+func (c ignoreCommand) Description() string {
+	return "List what the bot ignores, or ignore or stop ignoring a user, role or channel, or all bots or webhooks. Commands and events from anything ignored are dropped. Mentions may be used instead of IDs."
+}
+
+func (c ignoreCommand) Owner() {}
+
+func (c ignoreCommand) Execute(s Session, m *dg.Message) {
+	fields := strings.Fields(m.Content)
+	ignoring := true
+	if len(fields) > 0 && fields[0] == "remove" {
+		ignoring = false
+		fields = fields[1:]
+	}
+
+	var text string
+	switch {
+	case len(fields) == 0 && ignoring:
+		text = c.list(m.GuildID)
+	case len(fields) == 1 && (fields[0] == ignoreBots || fields[0] == ignoreWebhooks):
+		text = c.setSwitch(m.GuildID, fields[0], ignoring)
+	case len(fields) != 2 || !validIgnoreKind(fields[0]):
+		text = c.T(m.GuildID, "Only users, roles, channels, bots and webhooks can be ignored.")
+	default:
+		kind, id := fields[0], strings.Trim(fields[1], "<@!&#>")
+		err := c.SetIgnoring(kind, id, ignoring)
+		switch {
+		case err != nil:
+			c.Log("[ignore]", err)
+			text = c.T(m.GuildID, "The ignore list could not be changed.")
+		case ignoring:
+			text = c.T(m.GuildID, "Ignoring %s %s.", c.T(m.GuildID, kind), id)
+		default:
+			text = c.T(m.GuildID, "No longer ignoring %s %s.", c.T(m.GuildID, kind), id)
+		}
+	}
+
+	_, err := s.ChannelMessageSend(m.ChannelID, text)
+	if err != nil {
+		c.Log("[ignore]", err)
+	}
+}
+
+func (c ignoreCommand) setSwitch(guildID, name string, ignoring bool) string {
+	set := c.SetIgnoringBots
+	if name == ignoreWebhooks {
+		set = c.SetIgnoringWebhooks
+	}
+	err := set(ignoring)
+	switch {
+	case err != nil:
+		c.Log("[ignore]", err)
+		return c.T(guildID, "The ignore list could not be changed.")
+	case ignoring:
+		return c.T(guildID, "Ignoring %s.", c.T(guildID, name))
+	default:
+		return c.T(guildID, "No longer ignoring %s.", c.T(guildID, name))
+	}
+}
+
+func (c ignoreCommand) list(guildID string) string {
+	var lines []string
+	if c.IgnoringBots() {
+		lines = append(lines, c.T(guildID, "Ignoring bots."))
+	}
+	if c.IgnoringWebhooks() {
+		lines = append(lines, c.T(guildID, "Ignoring webhooks."))
+	}
+	for _, kind := range ignoreKinds {
+		if ids := c.IgnoreList(kind); len(ids) > 0 {
+			lines = append(lines, c.T(guildID, "Ignoring %s: %s", c.T(guildID, kind+"s"), strings.Join(ids, ", ")))
+		}
+	}
+	if len(lines) == 0 {
+		return c.T(guildID, "Nothing is ignored.")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,108 @@
+package bot_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestIgnore(t *testing.T) {
+	tests := []struct {
+		name    string
+		ignore  map[string]interface{}
+		change  func(b *bot.Bot) // runtime edits of the ignore lists
+		message func(m *dg.Message)
+		ran     bool
+	}{
+		{"plain", nil, nil, func(*dg.Message) {}, true},
+		{"bot", nil, nil, func(m *dg.Message) { m.Author.Bot = true }, false},
+		{"bot allowed", map[string]interface{}{"bots": false}, nil, func(m *dg.Message) { m.Author.Bot = true }, true},
+		{"webhook", nil, nil, func(m *dg.Message) { m.WebhookID = "w" }, false},
+		{"user", map[string]interface{}{"users": []string{"user"}}, nil, func(*dg.Message) {}, false},
+		{"role", map[string]interface{}{"roles": []string{"muted"}}, nil, func(m *dg.Message) {
+			m.Member = &dg.Member{Roles: []string{"member", "muted"}}
+		}, false},
+		{"channel", map[string]interface{}{"channels": []string{"c"}}, nil, func(*dg.Message) {}, false},
+		{"owner", map[string]interface{}{"channels": []string{"c"}}, nil, func(m *dg.Message) { m.Author.ID = "owner" }, true},
+		{"ignored at runtime", nil, func(b *bot.Bot) {
+			b.SetIgnoring(bot.IgnoreChannel, "c", true)
+		}, func(*dg.Message) {}, false},
+		{"unignored at runtime", map[string]interface{}{"users": []string{"user"}}, func(b *bot.Bot) {
+			b.SetIgnoring(bot.IgnoreUser, "user", false)
+		}, func(*dg.Message) {}, true},
+		{"bots allowed at runtime", nil, func(b *bot.Bot) {
+			b.SetIgnoringBots(false)
+		}, func(m *dg.Message) { m.Author.Bot = true }, true},
+		{"webhooks ignored at runtime", map[string]interface{}{"webhooks": false}, func(b *bot.Bot) {
+			b.SetIgnoringWebhooks(true)
+		}, func(m *dg.Message) { m.WebhookID = "w" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := map[string]interface{}{"owner": "owner", "sigil": "!"}
+			if tt.ignore != nil {
+				cfg["ignore"] = tt.ignore
+			}
+			b := bottest.NewBot(t, cfg)
+			b.AddCommand(bot.SimpleCommand("roll", nop, bot.SimpleCommandInfo{}))
+			if tt.change != nil {
+				tt.change(b)
+			}
+
+			m := bottest.Message("c", "user", "!roll")
+			tt.message(m)
+			if ran := b.Dispatch(bottest.NewSession(), "self", m); ran != tt.ran {
+				t.Errorf("ran = %v, want %v", ran, tt.ran)
+			}
+		})
+	}
+}
+
+func TestIgnoreList(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{
+		"owner":  "owner",
+		"sigil":  "!",
+		"ignore": map[string]interface{}{"users": []string{"a", "b"}},
+	})
+	if err := b.SetIgnoring(bot.IgnoreUser, "b", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetIgnoring(bot.IgnoreUser, "c", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetIgnoring("guild", "g", true); err == nil {
+		t.Error("ignored a guild without error")
+	}
+
+	if got, want := b.IgnoreList(bot.IgnoreUser), []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := b.IgnoreList(bot.IgnoreRole); len(got) != 0 {
+		t.Errorf("got roles %q, want none", got)
+	}
+}
+
+func TestIgnoreCommandSwitches(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	defer b.Stop()
+
+	var out bytes.Buffer
+	err := b.RunConsole(strings.NewReader("!ignore remove bots\n!ignore webhooks\n!ignore\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "No longer ignoring bots.\nIgnoring webhooks.\nIgnoring webhooks."
+	if got := strings.TrimSpace(out.String()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if b.IgnoringBots() || !b.IgnoringWebhooks() {
+		t.Errorf("ignoring bots = %v, webhooks = %v, want false, true", b.IgnoringBots(), b.IgnoringWebhooks())
+	}
+}
@@ -0,0 +1,426 @@
+// Package irc serves the bot's commands over IRC.
+//
+// Networks are configured under the "irc" key:
+//
+//	"irc": [
+//		{
+//			"server": "irc.libera.chat:6697",
+//			"tls": true,
+//			"nick": "stoopid",
+//			"channels": ["#stoopid"],
+//			"flood": {"burst": 5, "interval": "2s"}
+//		}
+//	]
+//
+// The bot answers commands in the configured channels and in private
+// messages. Embeds are sent as several lines of plain text. Outgoing
+// lines are rate limited: after a burst of lines, at most one line is
+// sent per interval, which by default is 5 lines and 2 seconds.
+package irc
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultBurst    = 5
+	defaultInterval = 2 * time.Second
+	registerTimeout = 30 * time.Second
+	queueSize       = 256
+	minBackoff      = time.Second
+	maxBackoff      = 5 * time.Minute
+)
+
+// Config describes a connection to an IRC network.
+type Config struct {
+	Server   string // host:port
+	TLS      bool
+	Password string
+	Nick     string
+	User     string
+	RealName string
+	Channels []string
+	Flood    struct {
+		Burst    int
+		Interval string
+	}
+}
+
+// Transports creates a transport for each entry under the "irc" config key.
+func Transports(c *config.Config) ([]bot.Transport, error) {
+	if !c.Exists("irc") {
+		return nil, nil
+	}
+
+	var cfgs []Config
+	err := c.Get("irc", &cfgs)
+	if err != nil {
+		return nil, err
+	}
+
+	transports := make([]bot.Transport, len(cfgs))
+	for i, cfg := range cfgs {
+		transports[i], err = New(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return transports, nil
+}
+
+// New creates a transport for an IRC network.
+// It does not connect until opened.
+func New(cfg Config) (*Transport, error) {
+	if cfg.Server == "" {
+		return nil, errors.New("irc: no server")
+	}
+	if cfg.Nick == "" {
+		cfg.Nick = "stoopid"
+	}
+	if cfg.User == "" {
+		cfg.User = cfg.Nick
+	}
+	if cfg.RealName == "" {
+		cfg.RealName = cfg.Nick
+	}
+
+	lim := &limiter{burst: cfg.Flood.Burst, interval: defaultInterval}
+	if lim.burst <= 0 {
+		lim.burst = defaultBurst
+	}
+	if cfg.Flood.Interval != "" {
+		var err error
+		lim.interval, err = time.ParseDuration(cfg.Flood.Interval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "irc %s", cfg.Server)
+		}
+	}
+
+	return &Transport{
+		cfg:   cfg,
+		lim:   lim,
+		queue: make(chan string, queueSize),
+		stop:  make(chan struct{}),
+	}, nil
+}
+
+// Transport is a connection to an IRC network.
+type Transport struct {
+	bot *bot.Bot
+
+	mu      sync.Mutex
+	conn    net.Conn
+	nick    string
+	writeMu sync.Mutex
+
+	lim   *limiter
+	queue chan string // lines waiting for the flood limit
+
+	stopOnce sync.Once
+	stop     chan struct{}
+
+	seq int64 // IDs for received messages, only used by the reader
+
+	// immutable
+	cfg Config
+}
+
+var _ bot.Transport = (*Transport)(nil)
+
+func (t *Transport) Name() string {
+	return "irc " + t.cfg.Server
+}
+
+func (t *Transport) logf(format string, v ...interface{}) {
+	t.bot.Logf("[%s] "+format, append([]interface{}{t.Name()}, v...)...)
+}
+
+// Open connects to the server and joins the configured channels.
+// If the connection is later lost, it is reestablished.
+func (t *Transport) Open(b *bot.Bot) error {
+	t.bot = b
+
+	r, err := t.connect()
+	if err != nil {
+		return err
+	}
+
+	go t.write()
+	go t.run(r)
+	return nil
+}
+
+// Close disconnects from the server.
+func (t *Transport) Close() error {
+	t.stopOnce.Do(func() { close(t.stop) })
+
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+
+	t.sendNow("QUIT :Goodbye")
+	return conn.Close()
+}
+
+func (t *Transport) stopped() bool {
+	select {
+	case <-t.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *Transport) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: registerTimeout}
+	if t.cfg.TLS {
+		host, _, _ := net.SplitHostPort(t.cfg.Server)
+		return tls.DialWithDialer(d, "tcp", t.cfg.Server, &tls.Config{ServerName: host})
+	}
+	return d.Dial("tcp", t.cfg.Server)
+}
+
+// connect dials the server and registers, returning once
+// the server has welcomed the bot and channels are joined.
+func (t *Transport) connect() (*bufio.Reader, error) {
+	conn, err := t.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.conn = conn
+	t.nick = t.cfg.Nick
+	t.mu.Unlock()
+
+	if t.cfg.Password != "" {
+		t.sendNow("PASS " + t.cfg.Password)
+	}
+	t.sendNow("NICK " + t.cfg.Nick)
+	t.sendNow("USER " + t.cfg.User + " 0 * :" + t.cfg.RealName)
+
+	conn.SetReadDeadline(time.Now().Add(registerTimeout))
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "registration failed")
+		}
+
+		m := parse(line)
+		switch m.command {
+		case "001": // RPL_WELCOME
+			t.mu.Lock()
+			t.nick = m.param(0)
+			t.mu.Unlock()
+			conn.SetReadDeadline(time.Time{})
+			for _, ch := range t.cfg.Channels {
+				t.sendNow("JOIN " + ch)
+			}
+			return r, nil
+		case "433": // ERR_NICKNAMEINUSE
+			t.mu.Lock()
+			t.nick += "_"
+			nick := t.nick
+			t.mu.Unlock()
+			t.sendNow("NICK " + nick)
+		case "ERROR":
+			conn.Close()
+			return nil, errors.Errorf("registration failed: %s", m.param(0))
+		default:
+			t.handle(m)
+		}
+	}
+}
+
+// run reads from the server until the transport is
+// closed, reconnecting whenever the connection is lost.
+func (t *Transport) run(r *bufio.Reader) {
+	backoff := minBackoff
+	for {
+		t.read(r)
+		if t.stopped() {
+			return
+		}
+
+		for {
+			t.logf("disconnected, reconnecting in %v", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-t.stop:
+				return
+			}
+
+			var err error
+			r, err = t.connect()
+			if err == nil {
+				break
+			}
+			t.logf("%v", err)
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		backoff = minBackoff
+	}
+}
+
+func (t *Transport) read(r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if !t.stopped() {
+				t.logf("%v", err)
+			}
+			return
+		}
+		t.handle(parse(line))
+	}
+}
+
+func (t *Transport) handle(m message) {
+	switch m.command {
+	case "PING":
+		t.sendNow("PONG :" + m.param(0))
+	case "NICK":
+		t.mu.Lock()
+		if nick(m.prefix) == t.nick {
+			t.nick = m.param(0)
+		}
+		t.mu.Unlock()
+	case "PRIVMSG":
+		t.privmsg(m)
+	case "ERROR":
+		t.logf("%s", m.param(0))
+	}
+}
+
+func (t *Transport) privmsg(m message) {
+	target, text := m.param(0), m.param(1)
+	if strings.HasPrefix(text, "\x01") {
+		return // CTCP
+	}
+
+	t.mu.Lock()
+	self := t.nick
+	t.mu.Unlock()
+
+	from := nick(m.prefix)
+	channelID := target
+	if !isChannel(target) {
+		channelID = from
+	}
+
+	t.seq++
+	msg := &dg.Message{
+		ID:        strconv.FormatInt(t.seq, 10),
+		ChannelID: channelID,
+		GuildID:   t.guildID(channelID),
+		Content:   text,
+		Timestamp: time.Now(),
+		Author:    &dg.User{ID: from, Username: from},
+	}
+	go t.bot.Dispatch(session{t}, self, msg)
+}
+
+func isChannel(target string) bool {
+	return strings.HasPrefix(target, "#") || strings.HasPrefix(target, "&")
+}
+
+// guildID returns the ID standing in for a guild:
+// the server for channels and nothing for private messages.
+func (t *Transport) guildID(channelID string) string {
+	if isChannel(channelID) {
+		return t.cfg.Server
+	}
+	return ""
+}
+
+// sendNow writes a line immediately, bypassing the flood limit.
+// It is used for registration and replies to pings.
+func (t *Transport) sendNow(line string) error {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn == nil {
+		return errors.New("not connected")
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(registerTimeout))
+	_, err := conn.Write([]byte(line + "\r\n"))
+	return err
+}
+
+// send queues a line to be written within the flood limit.
+func (t *Transport) send(line string) error {
+	select {
+	case t.queue <- line:
+		return nil
+	default:
+		return errors.New("send queue full")
+	}
+}
+
+// write sends queued lines, waiting as needed to respect the flood limit.
+func (t *Transport) write() {
+	for {
+		select {
+		case line := <-t.queue:
+			if d := t.lim.delay(time.Now()); d > 0 {
+				select {
+				case <-time.After(d):
+				case <-t.stop:
+					return
+				}
+			}
+			err := t.sendNow(line)
+			if err != nil {
+				t.logf("%v", err)
+			}
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// limiter implements the flood control described in RFC 1459 section 8.10:
+// a line may be sent immediately while the limiter's clock is less than
+// burst intervals ahead of the present, and each line advances the clock
+// by one interval.
+type limiter struct {
+	burst    int
+	interval time.Duration
+	next     time.Time
+}
+
+// delay returns how long to wait before sending a line at now.
+func (l *limiter) delay(now time.Time) time.Duration {
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(l.interval)
+	d := l.next.Sub(now) - time.Duration(l.burst)*l.interval
+	if d < 0 {
+		return 0
+	}
+	return d
+}
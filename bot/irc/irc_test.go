@@ -0,0 +1,227 @@
+package irc
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+// server is a stand-in IRC server that accepts a single client.
+type server struct {
+	t    *testing.T
+	ln   net.Listener
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newServer(t *testing.T) *server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &server{t: t, ln: ln}
+}
+
+func (s *server) accept() {
+	s.t.Helper()
+	conn, err := s.ln.Accept()
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.conn = conn
+	s.r = bufio.NewReader(conn)
+}
+
+func (s *server) close() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.ln.Close()
+}
+
+func (s *server) send(line string) {
+	s.t.Helper()
+	_, err := s.conn.Write([]byte(line + "\r\n"))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+func (s *server) expect(want string) {
+	s.t.Helper()
+	s.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		s.t.Fatalf("waiting for %q: %v", want, err)
+	}
+	if got := strings.TrimRight(line, "\r\n"); got != want {
+		s.t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// open connects a transport to the server and completes registration.
+func open(t *testing.T, srv *server, cfg Config, b *bot.Bot) *Transport {
+	cfg.Server = srv.ln.Addr().String()
+	tr, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- tr.Open(b) }()
+
+	srv.accept()
+	srv.expect("PASS secret")
+	srv.expect("NICK stoopid")
+	srv.send(":irc.test 433 * stoopid :Nickname is already in use")
+	srv.expect("USER stoopid 0 * :stoopid")
+	srv.expect("NICK stoopid_")
+	srv.send(":irc.test 001 stoopid_ :Welcome")
+	srv.expect("JOIN #test")
+
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func newBot(t *testing.T) *bot.Bot {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "123", "sigil": "!"})
+	b.AddCommand(bot.SimpleCommand("echo", func(s bot.Session, m *dg.Message) {
+		s.ChannelMessageSend(m.ChannelID, m.Content)
+	}, bot.SimpleCommandInfo{}))
+	b.AddCommand(bot.SimpleCommand("count", func(s bot.Session, m *dg.Message) {
+		s.ChannelMessageSend(m.ChannelID, "1\n2\n\n3\n4")
+	}, bot.SimpleCommandInfo{}))
+	b.AddCommand(bot.SimpleCommand("embed", func(s bot.Session, m *dg.Message) {
+		s.ChannelMessageSendEmbed(m.ChannelID, &dg.MessageEmbed{
+			Title:  "Title",
+			Fields: []*dg.MessageEmbedField{{Name: "Name", Value: "value"}},
+			Footer: &dg.MessageEmbedFooter{Text: "footer"},
+		})
+	}, bot.SimpleCommandInfo{}))
+	return b
+}
+
+func TestCommands(t *testing.T) {
+	srv := newServer(t)
+	defer srv.close()
+
+	cfg := Config{Password: "secret", Channels: []string{"#test"}}
+	cfg.Flood.Interval = "1ms"
+	tr := open(t, srv, cfg, newBot(t))
+	defer tr.Close()
+
+	srv.send("PING :irc.test")
+	srv.expect("PONG :irc.test")
+
+	srv.send(":alice!a@host PRIVMSG #test :!echo hello world")
+	srv.expect("PRIVMSG #test :hello world")
+
+	srv.send(":alice!a@host PRIVMSG stoopid_ :!echo psst")
+	srv.expect("PRIVMSG alice :psst")
+
+	srv.send(":alice!a@host PRIVMSG #test :!count")
+	srv.expect("PRIVMSG #test :1")
+	srv.expect("PRIVMSG #test :2")
+	srv.expect("PRIVMSG #test :3")
+	srv.expect("PRIVMSG #test :4")
+
+	srv.send(":alice!a@host PRIVMSG #test :!embed")
+	srv.expect("PRIVMSG #test :Title")
+	srv.expect("PRIVMSG #test :Name: value")
+	srv.expect("PRIVMSG #test :— footer")
+
+	// the bot's own messages are ignored
+	srv.send(":stoopid_!s@host PRIVMSG #test :!echo loop")
+	srv.send(":alice!a@host PRIVMSG #test :!echo done")
+	srv.expect("PRIVMSG #test :done")
+}
+
+func TestFloodLimit(t *testing.T) {
+	srv := newServer(t)
+	defer srv.close()
+
+	cfg := Config{Password: "secret", Channels: []string{"#test"}}
+	cfg.Flood.Burst = 2
+	cfg.Flood.Interval = "200ms"
+	tr := open(t, srv, cfg, newBot(t))
+	defer tr.Close()
+
+	start := time.Now()
+	srv.send(":alice!a@host PRIVMSG #test :!count")
+	for _, want := range []string{"1", "2", "3", "4"} {
+		srv.expect("PRIVMSG #test :" + want)
+	}
+	if d := time.Since(start); d < 400*time.Millisecond {
+		t.Errorf("sent 4 lines in %v with a burst of 2 and interval of 200ms", d)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := &limiter{burst: 3, interval: time.Second}
+	now := time.Unix(0, 0)
+
+	var got []time.Duration
+	for i := 0; i < 5; i++ {
+		got = append(got, l.delay(now))
+	}
+	want := []time.Duration{0, 0, 0, time.Second, 2 * time.Second}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got delays %v, want %v", got, want)
+	}
+
+	// the clock catches up after a pause
+	if d := l.delay(now.Add(time.Minute)); d != 0 {
+		t.Errorf("got delay %v after a pause, want 0", d)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want message
+	}{
+		{"PING :irc.test\r\n", message{"", "PING", []string{"irc.test"}}},
+		{":a!b@c PRIVMSG #chan :hello there", message{"a!b@c", "PRIVMSG", []string{"#chan", "hello there"}}},
+		{"@time=now :srv 001 nick :Welcome", message{"srv", "001", []string{"nick", "Welcome"}}},
+		{":srv MODE nick +i", message{"srv", "MODE", []string{"nick", "+i"}}},
+	}
+	for _, tt := range tests {
+		if got := parse(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parse(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	lines := split(long)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for _, line := range lines {
+		if len(line) > maxText {
+			t.Errorf("line of %d bytes exceeds %d", len(line), maxText)
+		}
+	}
+	if got := strings.Join(lines, " "); got != long {
+		t.Error("split lost text")
+	}
+
+	if got := split("a\n\nb"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("got %q, want empty lines dropped", got)
+	}
+
+	injected := "hi\rQUIT :bye\r\nx\x00PRIVMSG #c :y\r"
+	if got, want := split(injected), []string{"hi QUIT :bye", "x PRIVMSG #c :y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
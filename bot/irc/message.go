@@ -0,0 +1,109 @@
+package irc
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// message is a line received from the server.
+type message struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+// parse parses a line of the form
+// [@tags] [:prefix] command [params...] [:trailing].
+// Tags are ignored.
+func parse(line string) message {
+	var m message
+
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "@") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return m
+		}
+		line = strings.TrimLeft(line[i:], " ")
+	}
+	if strings.HasPrefix(line, ":") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			m.prefix = line[1:]
+			return m
+		}
+		m.prefix, line = line[1:i], strings.TrimLeft(line[i:], " ")
+	}
+
+	for line != "" {
+		if strings.HasPrefix(line, ":") {
+			m.params = append(m.params, line[1:])
+			break
+		}
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			i = len(line)
+		}
+		if m.command == "" {
+			m.command = strings.ToUpper(line[:i])
+		} else {
+			m.params = append(m.params, line[:i])
+		}
+		line = strings.TrimLeft(line[i:], " ")
+	}
+	return m
+}
+
+// nick returns the nickname part of a nick!user@host prefix.
+func nick(prefix string) string {
+	if i := strings.IndexAny(prefix, "!@"); i >= 0 {
+		return prefix[:i]
+	}
+	return prefix
+}
+
+func (m message) param(i int) string {
+	if i < len(m.params) {
+		return m.params[i]
+	}
+	return ""
+}
+
+// maxText is the most text sent in a single PRIVMSG. Lines are
+// limited to 512 bytes including the prefix the server adds when
+// relaying them, so this leaves room for a long nick!user@host.
+const maxText = 400
+
+// unsafe replaces the characters that would end or corrupt an IRC line,
+// so text cannot smuggle in commands of its own.
+var unsafe = strings.NewReplacer("\r", " ", "\n", " ", "\x00", " ")
+
+// clean makes text safe to send as part of a single IRC line.
+func clean(text string) string {
+	return unsafe.Replace(text)
+}
+
+// split breaks text into lines of at most maxText bytes,
+// preferring to break at spaces and never splitting a rune.
+// Empty lines are dropped, since IRC cannot send them.
+func split(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = clean(strings.TrimRight(line, "\r"))
+		for len(line) > maxText {
+			i := strings.LastIndexByte(line[:maxText], ' ')
+			if i <= 0 {
+				i = maxText
+				for i > 0 && !utf8.RuneStart(line[i]) {
+					i--
+				}
+			}
+			lines = append(lines, line[:i])
+			line = strings.TrimLeft(line[i:], " ")
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,114 @@
+package irc
+
+import (
+	"strings"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+)
+
+// session implements bot.Session on top of a transport.
+type session struct {
+	t *Transport
+}
+
+var _ bot.TextSession = session{}
+
+// PlainText implements bot.TextSession, since long text
+// is split between lines when it is sent.
+func (s session) PlainText() {}
+
+func (s session) ChannelMessageSend(channelID, content string) (*dg.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &dg.MessageSend{Content: content})
+}
+
+func (s session) ChannelMessageSendEmbed(channelID string, embed *dg.MessageEmbed) (*dg.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &dg.MessageSend{Embeds: []*dg.MessageEmbed{embed}})
+}
+
+func (s session) ChannelMessageSendComplex(channelID string, data *dg.MessageSend) (*dg.Message, error) {
+	lines := split(data.Content)
+	for _, e := range data.Embeds {
+		for _, line := range bot.EmbedText(e) {
+			lines = append(lines, split(line)...)
+		}
+	}
+	for _, f := range data.Files {
+		lines = append(lines, "[attachment: "+clean(f.Name)+"]")
+	}
+
+	for _, line := range lines {
+		err := s.t.send("PRIVMSG " + channelID + " :" + line)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &dg.Message{ChannelID: channelID, Content: strings.Join(lines, "\n")}, nil
+}
+
+func (s session) ChannelMessageEditComplex(m *dg.MessageEdit) (*dg.Message, error) {
+	return nil, bot.ErrUnsupported
+}
+
+func (s session) ChannelMessageDelete(channelID, messageID string) error {
+	return bot.ErrUnsupported
+}
+
+func (s session) MessageReactionAdd(channelID, messageID, emojiID string) error {
+	return bot.ErrUnsupported
+}
+
+func (s session) MessageReactionsRemoveAll(channelID, messageID string) error {
+	return bot.ErrUnsupported
+}
+
+func (s session) Channel(channelID string) (*dg.Channel, error) {
+	ch := &dg.Channel{
+		ID:      channelID,
+		GuildID: s.t.guildID(channelID),
+		Name:    channelID,
+		Type:    dg.ChannelTypeDM,
+	}
+	if isChannel(channelID) {
+		ch.Type = dg.ChannelTypeGuildText
+	}
+	return ch, nil
+}
+
+// Guild returns a guild standing in for the server.
+func (s session) Guild(guildID string) (*dg.Guild, error) {
+	if guildID != s.t.cfg.Server {
+		return nil, dg.ErrStateNotFound
+	}
+	return &dg.Guild{ID: guildID, Name: guildID}, nil
+}
+
+func (s session) GuildMember(guildID, userID string) (*dg.Member, error) {
+	return &dg.Member{GuildID: guildID, User: &dg.User{ID: userID, Username: userID}}, nil
+}
+
+// GuildMemberNickname changes the bot's nick on the network.
+// Other users' nicks cannot be changed.
+func (s session) GuildMemberNickname(guildID, userID, nickname string) error {
+	if userID != "@me" {
+		return bot.ErrUnsupported
+	}
+	if nickname == "" {
+		nickname = s.t.cfg.Nick
+	}
+	return s.t.send("NICK " + clean(nickname))
+}
+
+// UserChannelPermissions reports every permission, since
+// IRC has nothing to restrict what the bot can send.
+func (s session) UserChannelPermissions(userID, channelID string) (int64, error) {
+	return dg.PermissionAll, nil
+}
+
+func (s session) SetAvatar(avatar string) error {
+	return bot.ErrUnsupported
+}
+
+func (s session) SetStatus(game string) error {
+	return bot.ErrUnsupported
+}
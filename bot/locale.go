@@ -0,0 +1,85 @@
+package bot
+
+import (
+	"github.com/njhanley/stoopid/i18n"
+	"github.com/pkg/errors"
+)
+
+// localeConfig is read from the "i18n" config key:
+//
+//	"i18n": {
+//		"path": "locales",
+//		"default": "en",
+//		"guilds": {"1234": "de"}
+//	}
+//
+// Locales are loaded from the files in path, as described in package i18n.
+type localeConfig struct {
+	Path    string
+	Default string
+	Guilds  map[string]string
+}
+
+func (b *Bot) loadLocales() error {
+	b.locales = localeConfig{Default: i18n.Default}
+	if b.Config.Exists("i18n") {
+		err := b.Config.Get("i18n", &b.locales)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.Catalog = new(i18n.Catalog)
+	if b.locales.Path != "" {
+		var err error
+		b.Catalog, err = i18n.Load(b.locales.Path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func localeKey(guildID string) string {
+	return "locale/" + guildID
+}
+
+// Locale returns the locale of a guild: the one set with SetLocale, or
+// else the one configured for the guild, or else the default locale.
+// Direct messages, which have no guild, use the default locale.
+func (b *Bot) Locale(guildID string) string {
+	if guildID == "" {
+		return b.locales.Default
+	}
+	var locale string
+	if b.Store.Get(localeKey(guildID), &locale) == nil {
+		return locale
+	}
+	if locale, ok := b.locales.Guilds[guildID]; ok {
+		return locale
+	}
+	return b.locales.Default
+}
+
+// SetLocale sets the locale of a guild, which persists across restarts.
+// The empty locale resets the guild to its configured locale.
+func (b *Bot) SetLocale(guildID, locale string) error {
+	if locale == "" {
+		return b.Store.Delete(localeKey(guildID))
+	}
+	if locale != i18n.Default && !b.Catalog.Has(locale) {
+		return errors.Errorf("unknown locale %q", locale)
+	}
+	return b.Store.Set(localeKey(guildID), locale)
+}
+
+// T translates a message for a guild, formatting it with args if any are given.
+func (b *Bot) T(guildID, msg string, args ...interface{}) string {
+	return b.Catalog.Translate(b.Locale(guildID), msg, args...)
+}
+
+// FormatNumber formats a number for a guild with the given number of decimal
+// places, or as few as needed to represent it exactly if decimals is negative.
+func (b *Bot) FormatNumber(guildID string, f float64, decimals int) string {
+	return b.Catalog.FormatNumber(b.Locale(guildID), f, decimals)
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultPanicLimit  = 3
+	defaultPanicWindow = 10 * time.Minute
+)
+
+// panicConfig is read from the "panics" config key.
+type panicConfig struct {
+	Report bool    // send the owner a direct message about each panic
+	Limit  *int    // panics within the window that disable a plugin, 0 for never
+	Window *string // as for time.ParseDuration
+}
+
+// panicTracker counts recent panics by plugin.
+type panicTracker struct {
+	report bool
+	limit  int
+	window time.Duration
+
+	mu    sync.Mutex
+	times map[string][]time.Time
+}
+
+func (b *Bot) loadPanicCfg() error {
+	var cfg panicConfig
+	if b.Config.Exists("panics") {
+		err := b.Config.Get("panics", &cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.panics = &panicTracker{
+		report: cfg.Report,
+		limit:  defaultPanicLimit,
+		window: defaultPanicWindow,
+		times:  make(map[string][]time.Time),
+	}
+	if cfg.Limit != nil {
+		if *cfg.Limit < 0 {
+			return errors.Errorf("panics: limit cannot be negative: %d", *cfg.Limit)
+		}
+		b.panics.limit = *cfg.Limit
+	}
+	if cfg.Window != nil {
+		var err error
+		b.panics.window, err = time.ParseDuration(*cfg.Window)
+		if err != nil {
+			return errors.Wrap(err, "panics: window")
+		}
+	}
+	return nil
+}
+
+// record records a panic in a plugin and reports whether the plugin
+// has now panicked too often and should be disabled.
+func (t *panicTracker) record(plugin string) bool {
+	if t.limit == 0 {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	recent := t.times[plugin][:0]
+	for _, at := range t.times[plugin] {
+		if now.Sub(at) < t.window {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+	if len(recent) >= t.limit {
+		delete(t.times, plugin)
+		return true
+	}
+	t.times[plugin] = recent
+	return false
+}
+
+// safely calls fn, recovering a panic in it, and reports whether fn
+// panicked. what describes fn in the log, and plugin names the plugin
+// it belongs to, if any, which is disabled if it panics too often.
+func (b *Bot) safely(what, plugin string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			b.panicked(what, plugin, r, debug.Stack())
+		}
+	}()
+	fn()
+	return false
+}
+
+// safeHandler wraps a discordgo event handler so that it recovers panics.
+func (b *Bot) safeHandler(handler interface{}, plugin string) interface{} {
+	v := reflect.ValueOf(handler)
+	if v.Kind() != reflect.Func {
+		return handler
+	}
+	what := fmt.Sprintf("%v handler", v.Type())
+	return reflect.MakeFunc(v.Type(), func(args []reflect.Value) []reflect.Value {
+		var results []reflect.Value
+		b.safely(what, plugin, func() { results = v.Call(args) })
+		return results
+	}).Interface()
+}
+
+func (b *Bot) panicked(what, plugin string, r interface{}, stack []byte) {
+	if plugin != "" {
+		what += fmt.Sprintf(" of plugin %q", plugin)
+	}
+	b.Logf("[panic] %s: %v\n%s", what, r, stack)
+	b.reportPanic(fmt.Sprintf("Panic in %s: %v", what, r))
+
+	if plugin == "" || !b.panics.record(plugin) {
+		return
+	}
+	// the plugin may be unloaded from within one of its own
+	// handlers, which could be holding locks unloading needs
+	go func() {
+		err := b.UnloadPlugin(plugin)
+		if err != nil {
+			b.Logf("[panic] could not disable plugin %q: %v", plugin, err)
+			return
+		}
+		text := fmt.Sprintf("Disabled plugin %q after %d panics within %v.", plugin, b.panics.limit, b.panics.window)
+		b.Logf("[panic] %s", text)
+		b.reportPanic(text)
+	}()
+}
+
+// reportPanic sends text to the owner by direct message if configured to.
+func (b *Bot) reportPanic(text string) {
+	if !b.panics.report || b.owner == "" || b.token == "" {
+		return
+	}
+	go func() {
+		ch, err := b.Session.UserChannelCreate(b.owner)
+		if err == nil {
+			_, err = b.Session.ChannelMessageSend(ch.ID, truncate(text, MaxMessageLength))
+		}
+		if err != nil {
+			b.Logf("[panic] %v", err)
+		}
+	}()
+}
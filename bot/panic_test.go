@@ -0,0 +1,87 @@
+package bot_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestCommandPanics(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{
+		"owner":  "owner",
+		"sigil":  "!",
+		"panics": map[string]interface{}{"limit": 2, "window": "1m"},
+	})
+	err := b.AddPlugin(bot.SimplePlugin("crashy", func(b *bot.Bot) error {
+		b.AddCommand(bot.SimpleCommand("crash", func(s bot.Session, m *dg.Message) {
+			var answers []string
+			s.ChannelMessageSend(m.ChannelID, answers[len(m.Content)])
+		}, bot.SimpleCommandInfo{}))
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddCommand(bot.SimpleCommand("roll", func(s bot.Session, m *dg.Message) {
+		s.ChannelMessageSend(m.ChannelID, "4")
+	}, bot.SimpleCommandInfo{}))
+
+	s := bottest.NewSession()
+	for i := 0; i < 2; i++ {
+		if !b.Dispatch(s, "self", bottest.Message("c", "user", "!crash")) {
+			t.Fatalf("crash %d did not run", i)
+		}
+	}
+	b.Dispatch(s, "self", bottest.Message("c", "user", "!roll"))
+
+	want := []string{"Something went wrong running that command.", "Something went wrong running that command.", "4"}
+	if got := s.Contents(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// the plugin is disabled in the background
+	for i := 0; b.GetPlugin("crashy") != nil; i++ {
+		if i == 100 {
+			t.Fatal("plugin not disabled after panicking twice")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if b.GetCommand("crash") != nil {
+		t.Error("command of disabled plugin still loaded")
+	}
+}
+
+func TestEventPanicsDisablePlugin(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{
+		"owner":  "owner",
+		"sigil":  "!",
+		"panics": map[string]interface{}{"limit": 3},
+	})
+	err := b.AddPlugin(bot.SimplePlugin("listener", func(b *bot.Bot) error {
+		b.On("boom", func(*bot.CustomEvent) { panic("boom") })
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		b.Emit("boom", nil)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if b.GetPlugin("listener") == nil {
+		t.Fatal("plugin disabled before reaching the limit")
+	}
+
+	b.Emit("boom", nil)
+	for i := 0; b.GetPlugin("listener") != nil; i++ {
+		if i == 100 {
+			t.Fatal("plugin not disabled after reaching the limit")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,5 +1,11 @@
 package bot
 
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
 // Plugin is the interface for extending a bot.
 // Plugins are identified by their names; loading multiple
 // plugins with the same name is allowed but all but the last
@@ -27,3 +33,132 @@ func (p *simplePlugin) Name() string {
 func (p *simplePlugin) Load(b *Bot) error {
 	return p.load(b)
 }
+
+// UnloadablePlugin is an interface for plugins that need to
+// release resources when they are unloaded. Commands, event handlers,
+// event subscriptions and job handlers added while loading a plugin
+// are removed without it.
+type UnloadablePlugin interface {
+	Plugin
+	Unload(*Bot)
+}
+
+// DependentPlugin is an interface for plugins that use other plugins.
+// Plugins named by Dependencies must be loaded before the plugin
+// and loading fails without them. Plugins named by
+// OptionalDependencies are loaded first if they are present.
+type DependentPlugin interface {
+	Plugin
+	Dependencies() []string
+	OptionalDependencies() []string
+}
+
+type dependentPlugin struct {
+	Plugin
+	deps, optional []string
+}
+
+func (p dependentPlugin) Dependencies() []string {
+	return append([]string(nil), p.deps...)
+}
+
+func (p dependentPlugin) OptionalDependencies() []string {
+	return append([]string(nil), p.optional...)
+}
+
+// Unload unloads the decorated plugin, so wrapping a plugin
+// does not hide that it implements UnloadablePlugin.
+func (p dependentPlugin) Unload(b *Bot) {
+	if up, ok := p.Plugin.(UnloadablePlugin); ok {
+		up.Unload(b)
+	}
+}
+
+// ToDependentPlugin decorates a plugin with
+// dependencies, implementing DependentPlugin.
+func ToDependentPlugin(p Plugin, deps, optional []string) DependentPlugin {
+	return dependentPlugin{p, deps, optional}
+}
+
+func dependencies(p Plugin) (deps, optional []string) {
+	if dp, ok := p.(DependentPlugin); ok {
+		return dp.Dependencies(), dp.OptionalDependencies()
+	}
+	return nil, nil
+}
+
+// sortPlugins orders plugins so that each comes after its dependencies,
+// otherwise preserving the given order. Dependencies for which loaded
+// reports true are considered satisfied.
+func sortPlugins(plugins []Plugin, loaded func(name string) bool) ([]Plugin, error) {
+	byName := make(map[string]int, len(plugins))
+	for i, p := range plugins {
+		if name := p.Name(); name != "" {
+			byName[name] = i
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(plugins))
+	sorted := make([]Plugin, 0, len(plugins))
+	var path []string
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		p := plugins[i]
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			j := 0
+			for path[j] != p.Name() {
+				j++
+			}
+			cycle := append(path[j:], p.Name())
+			return errors.Errorf("plugin dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[i] = visiting
+		path = append(path, p.Name())
+
+		deps, optional := dependencies(p)
+		for _, name := range deps {
+			j, ok := byName[name]
+			if !ok {
+				if loaded(name) {
+					continue
+				}
+				return errors.Errorf("plugin %q requires missing plugin %q", p.Name(), name)
+			}
+			err := visit(j)
+			if err != nil {
+				return err
+			}
+		}
+		for _, name := range optional {
+			if j, ok := byName[name]; ok {
+				err := visit(j)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[i] = visited
+		sorted = append(sorted, p)
+		return nil
+	}
+
+	for i := range plugins {
+		err := visit(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
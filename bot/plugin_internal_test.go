@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortPlugins(t *testing.T) {
+	plugin := func(name string, deps, optional []string) Plugin {
+		p := SimplePlugin(name, func(*Bot) error { return nil })
+		if deps == nil && optional == nil {
+			return p
+		}
+		return ToDependentPlugin(p, deps, optional)
+	}
+
+	tests := []struct {
+		name    string
+		plugins []Plugin
+		loaded  []string
+		want    []string
+		err     string
+	}{
+		{
+			name:    "independent",
+			plugins: []Plugin{plugin("a", nil, nil), plugin("b", nil, nil), plugin("c", nil, nil)},
+			want:    []string{"a", "b", "c"},
+		},
+		{
+			name:    "dependency first",
+			plugins: []Plugin{plugin("a", []string{"b"}, nil), plugin("b", []string{"c"}, nil), plugin("c", nil, nil)},
+			want:    []string{"c", "b", "a"},
+		},
+		{
+			name:    "shared dependency",
+			plugins: []Plugin{plugin("a", []string{"c"}, nil), plugin("b", []string{"c"}, nil), plugin("c", nil, nil)},
+			want:    []string{"c", "a", "b"},
+		},
+		{
+			name:    "optional present",
+			plugins: []Plugin{plugin("a", nil, []string{"b"}), plugin("b", nil, nil)},
+			want:    []string{"b", "a"},
+		},
+		{
+			name:    "optional missing",
+			plugins: []Plugin{plugin("a", nil, []string{"b"})},
+			want:    []string{"a"},
+		},
+		{
+			name:    "already loaded",
+			plugins: []Plugin{plugin("a", []string{"b"}, nil)},
+			loaded:  []string{"b"},
+			want:    []string{"a"},
+		},
+		{
+			name:    "missing",
+			plugins: []Plugin{plugin("a", []string{"b"}, nil)},
+			err:     `plugin "a" requires missing plugin "b"`,
+		},
+		{
+			name:    "cycle",
+			plugins: []Plugin{plugin("a", []string{"b"}, nil), plugin("b", []string{"c"}, nil), plugin("c", []string{"b"}, nil)},
+			err:     "plugin dependency cycle: b -> c -> b",
+		},
+		{
+			name:    "optional cycle",
+			plugins: []Plugin{plugin("a", nil, []string{"b"}), plugin("b", []string{"a"}, nil)},
+			err:     "plugin dependency cycle: a -> b -> a",
+		},
+		{
+			name:    "self",
+			plugins: []Plugin{plugin("a", []string{"a"}, nil)},
+			err:     "plugin dependency cycle: a -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded := func(name string) bool {
+				for _, l := range tt.loaded {
+					if l == name {
+						return true
+					}
+				}
+				return false
+			}
+			sorted, err := sortPlugins(tt.plugins, loaded)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, p := range sorted {
+				got = append(got, p.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got order %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
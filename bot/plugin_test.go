@@ -0,0 +1,99 @@
+package bot_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+type unloadable struct {
+	bot.Plugin
+	unloaded bool
+}
+
+func (p *unloadable) Unload(*bot.Bot) {
+	p.unloaded = true
+}
+
+func TestUnloadDependentPlugin(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	base := bot.SimplePlugin("base", func(*bot.Bot) error { return nil })
+	p := &unloadable{Plugin: bot.SimplePlugin("dep", func(*bot.Bot) error { return nil })}
+	if err := b.AddPlugins(bot.ToDependentPlugin(p, []string{"base"}, nil), base); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.UnloadPlugin("base"); err == nil || !strings.Contains(err.Error(), "required by") {
+		t.Errorf("got %v, want base to be required by dep", err)
+	}
+	if err := b.UnloadPlugin("dep"); err != nil {
+		t.Fatal(err)
+	}
+	if !p.unloaded {
+		t.Error("wrapped plugin was not unloaded")
+	}
+	if err := b.UnloadPlugin("base"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadGetsDependency(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	base := bot.SimplePlugin("base", func(*bot.Bot) error { return nil })
+	var got bot.Plugin
+	dep := bot.SimplePlugin("dep", func(b *bot.Bot) error {
+		got = b.GetPlugin("base")
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- b.AddPlugins(base, bot.ToDependentPlugin(dep, []string{"base"}, nil)) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("loading a plugin that looks up its dependency deadlocked")
+	}
+	if got != base {
+		t.Errorf("got %v from GetPlugin in Load, want the dependency", got)
+	}
+}
+
+func TestGetService(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	b.AddService("count", 3)
+	b.AddService("stringer", bot.SimplePlugin("x", nil))
+
+	var n int
+	if err := b.GetService("count", &n); err != nil || n != 3 {
+		t.Errorf("got %d, %v, want 3", n, err)
+	}
+	var p bot.Plugin
+	if err := b.GetService("stringer", &p); err != nil || p.Name() != "x" {
+		t.Errorf("got %v, %v, want the plugin as an interface", p, err)
+	}
+
+	tests := []struct {
+		name string
+		ptr  interface{}
+		err  string
+	}{
+		{"count", new(string), `service "count" is int, not string`},
+		{"count", n, "non-pointer int passed to GetService"},
+		{"count", (*int)(nil), "non-pointer *int passed to GetService"},
+		{"stringer", new(fmt.Stringer), `not fmt.Stringer`},
+		{"missing", new(int), `service "missing" not found`},
+	}
+	for _, tt := range tests {
+		err := b.GetService(tt.name, tt.ptr)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s into %T: got %v, want %q", tt.name, tt.ptr, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// errQueueFull is returned when a command cannot be queued.
+var errQueueFull = errors.New("command queue is full")
+
+const (
+	defaultWorkers   = 8
+	defaultQueueSize = 64
+)
+
+// poolConfig is read from the "pool" config key.
+type poolConfig struct {
+	Workers int // most commands run at once
+	Queue   int // most commands waiting to run
+}
+
+// PoolStats describes the worker pool commands run in.
+type PoolStats struct {
+	Workers   int    // most commands run at once
+	Busy      int    // commands running
+	Queued    int    // commands waiting to run
+	QueueSize int    // most commands waiting to run
+	MaxQueued int    // most commands that have waited at once
+	Completed uint64 // commands run
+	Rejected  uint64 // commands not run because the queue was full
+
+	AvgWait time.Duration // mean time commands waited to run
+	MaxWait time.Duration // longest time a command waited to run
+}
+
+// workerPool limits how many commands run at once. Commands beyond the
+// limit wait in a queue for each guild, and the queues take turns, so
+// a burst of commands in one guild does not hold up the others.
+type workerPool struct {
+	mu      sync.Mutex
+	queues  map[string][]*poolWaiter // by key
+	turns   []string                 // keys with waiting commands, in turn
+	holders map[*dg.Message]bool     // invoking messages of commands holding a worker
+	stats   PoolStats
+	waited  time.Duration // total of the waits
+}
+
+type poolWaiter struct {
+	start   chan struct{} // closed when the command may run
+	since   time.Time
+	resumed bool // whether the command ran before and is not counted again
+}
+
+func newWorkerPool(workers, queue int) *workerPool {
+	return &workerPool{
+		queues:  make(map[string][]*poolWaiter),
+		holders: make(map[*dg.Message]bool),
+		stats:   PoolStats{Workers: workers, QueueSize: queue},
+	}
+}
+
+func (b *Bot) loadPoolCfg() error {
+	cfg := poolConfig{Workers: defaultWorkers, Queue: defaultQueueSize}
+	if b.Config.Exists("pool") {
+		err := b.Config.Get("pool", &cfg)
+		if err != nil {
+			return err
+		}
+		if cfg.Workers < 1 || cfg.Queue < 0 {
+			return errors.Errorf("pool needs at least 1 worker and a queue of 0 or more, not %d and %d", cfg.Workers, cfg.Queue)
+		}
+	}
+	b.pool = newWorkerPool(cfg.Workers, cfg.Queue)
+	return nil
+}
+
+// poolKey returns the key of the queue a message's command waits in:
+// its guild's, or its channel's if it is a direct message.
+func poolKey(m *dg.Message) string {
+	if m.GuildID != "" {
+		return m.GuildID
+	}
+	return "dm/" + m.ChannelID
+}
+
+// run calls fn once a worker is free, waiting in the queue with
+// the given key until then, or returns errQueueFull without
+// calling fn if the queue is full.
+func (p *workerPool) run(key string, fn func()) error {
+	err := p.acquire(key, false)
+	if err != nil {
+		return err
+	}
+	defer p.release(true)
+	fn()
+	return nil
+}
+
+// runCommand runs fn, the command invoked by m, as for run.
+// While the command waits in idle, its worker is lent to others.
+func (p *workerPool) runCommand(m *dg.Message, fn func()) error {
+	return p.run(poolKey(m), func() {
+		p.hold(m, true)
+		defer p.hold(m, false)
+		fn()
+	})
+}
+
+// idle calls fn, which waits on a user rather than doing any work.
+// If the command invoked by m holds a worker, the worker is freed
+// for other commands until fn returns, when the command waits
+// for a worker again, in turn but regardless of the queue's size.
+func (p *workerPool) idle(m *dg.Message, fn func()) {
+	p.mu.Lock()
+	held := p.holders[m]
+	p.mu.Unlock()
+	if !held {
+		fn()
+		return
+	}
+
+	p.hold(m, false)
+	p.release(false)
+	defer func() {
+		p.acquire(poolKey(m), true)
+		p.hold(m, true)
+	}()
+	fn()
+}
+
+func (p *workerPool) hold(m *dg.Message, held bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if held {
+		p.holders[m] = true
+	} else {
+		delete(p.holders, m)
+	}
+}
+
+// acquire takes a worker, waiting in the queue with the given key if
+// none is free. Unless resuming a command, it returns errQueueFull
+// if the queue is full.
+func (p *workerPool) acquire(key string, resume bool) error {
+	p.mu.Lock()
+	if p.stats.Busy < p.stats.Workers {
+		p.stats.Busy++
+		p.mu.Unlock()
+		return nil
+	}
+	if !resume && p.stats.Queued >= p.stats.QueueSize {
+		p.stats.Rejected++
+		p.mu.Unlock()
+		return errQueueFull
+	}
+	w := &poolWaiter{start: make(chan struct{}), since: time.Now(), resumed: resume}
+	if len(p.queues[key]) == 0 {
+		p.turns = append(p.turns, key)
+	}
+	p.queues[key] = append(p.queues[key], w)
+	p.stats.Queued++
+	if p.stats.Queued > p.stats.MaxQueued {
+		p.stats.MaxQueued = p.stats.Queued
+	}
+	p.mu.Unlock()
+
+	// the worker is handed over by the command finishing before
+	<-w.start
+	return nil
+}
+
+// release hands a worker to the next command waiting, taking
+// the queues in turn. completed is whether its command finished.
+func (p *workerPool) release(completed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if completed {
+		p.stats.Completed++
+	}
+
+	if len(p.turns) == 0 {
+		p.stats.Busy--
+		return
+	}
+	key := p.turns[0]
+	p.turns = p.turns[1:]
+	queue := p.queues[key]
+	w := queue[0]
+	if len(queue) > 1 {
+		p.queues[key] = queue[1:]
+		p.turns = append(p.turns, key)
+	} else {
+		delete(p.queues, key)
+	}
+	p.stats.Queued--
+
+	if !w.resumed {
+		wait := time.Since(w.since)
+		p.waited += wait
+		if wait > p.stats.MaxWait {
+			p.stats.MaxWait = wait
+		}
+	}
+	close(w.start)
+}
+
+func (p *workerPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	// commands that did not wait count as waiting for no time
+	if started := stats.Completed + uint64(stats.Busy); started > 0 {
+		stats.AvgWait = p.waited / time.Duration(started)
+	}
+	return stats
+}
+
+// PoolStats returns the state of the worker pool commands run in.
+func (b *Bot) PoolStats() PoolStats {
+	return b.pool.Stats()
+}
+
+type poolCommand struct {
+	*Bot
+}
+
+func (c poolCommand) Name() string {
+	return "pool"
+}
+
+func (c poolCommand) Comment() string {
+	return "show command queue statistics"
+}
+
+func (c poolCommand) Usage() []string {
+	return []string{"pool"}
+}
+
+func (c poolCommand) Description() string {
+	return "Show how many commands are running and waiting to run, how many have been turned away because the queue was full, and how long commands wait."
+}
+
+func (c poolCommand) Owner() {}
+
+func (c poolCommand) Execute(s Session, m *dg.Message) {
+	st := c.PoolStats()
+	lines := []string{
+		c.T(m.GuildID, "Running: %d/%d", st.Busy, st.Workers),
+		c.T(m.GuildID, "Queued: %d/%d (at most %d)", st.Queued, st.QueueSize, st.MaxQueued),
+		c.T(m.GuildID, "Completed: %d, rejected: %d", st.Completed, st.Rejected),
+		c.T(m.GuildID, "Wait: %v on average, %v at most", st.AvgWait.Round(time.Millisecond), st.MaxWait.Round(time.Millisecond)),
+	}
+
+	_, err := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("```\n%s\n```", strings.Join(lines, "\n")))
+	if err != nil {
+		c.Log("[pool]", err)
+	}
+}
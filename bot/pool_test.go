@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+// queue runs a command named name in p with the given key once the commands
+// queued before it have started, appending name to ran when it runs.
+func queue(t *testing.T, p *workerPool, wg *sync.WaitGroup, mu *sync.Mutex, ran *[]string, key, name string) {
+	queued := p.Stats().Queued
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := p.run(key, func() {
+			mu.Lock()
+			*ran = append(*ran, name)
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	for p.Stats().Queued == queued {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWorkerPoolFairness(t *testing.T) {
+	p := newWorkerPool(1, 10)
+	block := make(chan struct{})
+	started := make(chan struct{})
+	go p.run("g1", func() {
+		close(started)
+		<-block
+	})
+	<-started
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ran []string
+	)
+	for _, c := range []struct{ key, name string }{
+		{"g1", "a"}, {"g1", "b"}, {"g1", "c"}, {"g2", "d"}, {"g3", "e"}, {"g2", "f"},
+	} {
+		queue(t, p, &wg, &mu, &ran, c.key, c.name)
+	}
+	if st := p.Stats(); st.Busy != 1 || st.Queued != 6 || st.MaxQueued != 6 {
+		t.Errorf("got %+v, want 1 busy and 6 queued", st)
+	}
+
+	close(block)
+	wg.Wait()
+	if want := []string{"a", "d", "e", "b", "f", "c"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %q, want %q", ran, want)
+	}
+	if st := p.Stats(); st.Busy != 0 || st.Queued != 0 || st.Completed != 7 || st.MaxWait <= 0 || st.AvgWait <= 0 {
+		t.Errorf("got %+v, want all 7 completed after waiting", st)
+	}
+}
+
+func TestWorkerPoolFull(t *testing.T) {
+	p := newWorkerPool(1, 1)
+	block := make(chan struct{})
+	started := make(chan struct{})
+	go p.run("g", func() {
+		close(started)
+		<-block
+	})
+	<-started
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ran []string
+	)
+	queue(t, p, &wg, &mu, &ran, "g", "queued")
+	if err := p.run("h", func() { t.Error("ran with a full queue") }); err != errQueueFull {
+		t.Errorf("got %v, want %v", err, errQueueFull)
+	}
+
+	close(block)
+	wg.Wait()
+	if st := p.Stats(); st.Completed != 2 || st.Rejected != 1 {
+		t.Errorf("got %+v, want 2 completed and 1 rejected", st)
+	}
+}
+
+func TestWorkerPoolIdle(t *testing.T) {
+	p := newWorkerPool(1, 0)
+	m := &dg.Message{GuildID: "g"}
+	answer := make(chan struct{})
+	waiting := make(chan struct{})
+	resumed := make(chan struct{})
+	go p.runCommand(m, func() {
+		// like a command waiting in Confirm
+		p.idle(m, func() {
+			close(waiting)
+			<-answer
+		})
+		close(resumed)
+	})
+	<-waiting
+
+	ran := false
+	if err := p.runCommand(&dg.Message{GuildID: "h"}, func() { ran = true }); err != nil || !ran {
+		t.Fatalf("got %v, want a command to run while another waits on a user", err)
+	}
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	go p.run("h", func() {
+		close(started)
+		<-block
+	})
+	<-started
+	close(answer)
+	for p.Stats().Queued == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-resumed:
+		t.Fatal("command resumed without a worker")
+	default:
+	}
+
+	// the command resumes once the worker is free, though the queue is full
+	close(block)
+	<-resumed
+	for p.Stats().Busy != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if st := p.Stats(); st.Completed != 3 || st.Rejected != 0 {
+		t.Errorf("got %+v, want 3 completed and none rejected", st)
+	}
+}
@@ -0,0 +1,267 @@
+package bot
+
+import (
+	"container/list"
+	"sync"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+const maxInvocations = 1000
+
+// reply identifies a message sent by a command.
+type reply struct {
+	ChannelID, ID string
+}
+
+// invocation records a message that invoked a command.
+type invocation struct {
+	id      string
+	content string // before the command was parsed
+	replies []reply
+}
+
+// replyTracker remembers the replies sent for the most recent invocations.
+type replyTracker struct {
+	mu      sync.Mutex
+	max     int
+	byID    map[string]*list.Element
+	order   *list.List // of *invocation, most recent first
+	running map[string]*run
+}
+
+// run counts the dispatches of a message still running and
+// remembers whether the message was deleted while they ran.
+type run struct {
+	n       int
+	deleted bool
+}
+
+func newReplyTracker(max int) *replyTracker {
+	return &replyTracker{
+		max:     max,
+		byID:    make(map[string]*list.Element),
+		order:   list.New(),
+		running: make(map[string]*run),
+	}
+}
+
+// add records an invocation, replacing any earlier record for
+// the same message and forgetting the oldest if there are too many.
+func (t *replyTracker) add(inv *invocation) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.addLocked(inv)
+}
+
+func (t *replyTracker) addLocked(inv *invocation) {
+	if e, ok := t.byID[inv.id]; ok {
+		t.order.Remove(e)
+	}
+	t.byID[inv.id] = t.order.PushFront(inv)
+
+	for t.order.Len() > t.max {
+		e := t.order.Back()
+		t.order.Remove(e)
+		delete(t.byID, e.Value.(*invocation).id)
+	}
+}
+
+// get returns the invocation recorded for a message, or nil.
+func (t *replyTracker) get(id string) *invocation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.byID[id]
+	if !ok {
+		return nil
+	}
+	return e.Value.(*invocation)
+}
+
+// remove forgets the invocation recorded for a message and returns it, or nil.
+func (t *replyTracker) remove(id string) *invocation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.removeLocked(id)
+}
+
+func (t *replyTracker) removeLocked(id string) *invocation {
+	e, ok := t.byID[id]
+	if !ok {
+		return nil
+	}
+	t.order.Remove(e)
+	delete(t.byID, id)
+	return e.Value.(*invocation)
+}
+
+// start notes that a message is being dispatched, so that
+// deleting it meanwhile is not missed.
+func (t *replyTracker) start(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.running[id]
+	if !ok {
+		r = new(run)
+		t.running[id] = r
+	}
+	r.n++
+}
+
+// finish notes that a dispatch of a message has finished and records
+// inv in place of any earlier invocation, or just forgets the earlier
+// one if inv is nil. It reports whether the message was deleted while
+// it was being dispatched, in which case inv is not recorded.
+func (t *replyTracker) finish(id string, inv *invocation) (deleted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r, ok := t.running[id]; ok {
+		deleted = r.deleted
+		if r.n--; r.n == 0 {
+			delete(t.running, id)
+		}
+	}
+	if inv == nil || deleted {
+		t.removeLocked(id)
+		return deleted
+	}
+	t.addLocked(inv)
+	return false
+}
+
+// deleted forgets the invocation recorded for a deleted message and
+// returns it, or nil. If the message is being dispatched, finish will
+// report that it was deleted.
+func (t *replyTracker) deleted(id string) *invocation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if r, ok := t.running[id]; ok {
+		r.deleted = true
+	}
+	return t.removeLocked(id)
+}
+
+// replySession is a Session that records the messages a command sends.
+// When a command is rerun, its previous replies are reused in order:
+// each is edited to hold the new reply if possible, or else deleted
+// and replaced with a new message.
+type replySession struct {
+	Session
+	id string // of the invoking message
+
+	mu      sync.Mutex
+	old     []reply
+	sent    []reply
+	deleted bool // whether the command deleted the invoking message
+}
+
+func (rs *replySession) ChannelMessageSend(channelID, content string) (*dg.Message, error) {
+	return rs.ChannelMessageSendComplex(channelID, &dg.MessageSend{Content: content})
+}
+
+func (rs *replySession) ChannelMessageSendEmbed(channelID string, embed *dg.MessageEmbed) (*dg.Message, error) {
+	return rs.ChannelMessageSendComplex(channelID, &dg.MessageSend{Embeds: []*dg.MessageEmbed{embed}})
+}
+
+func (rs *replySession) ChannelMessageSendComplex(channelID string, data *dg.MessageSend) (*dg.Message, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if len(rs.old) > 0 && rs.old[0].ChannelID == channelID {
+		prev := rs.old[0]
+		rs.old = rs.old[1:]
+
+		// attachments cannot be replaced by editing
+		if len(data.Files) == 0 {
+			// buttons left on the previous reply are cleared
+			components := data.Components
+			if components == nil {
+				components = []dg.MessageComponent{}
+			}
+			m, err := rs.Session.ChannelMessageEditComplex(&dg.MessageEdit{
+				ID:              prev.ID,
+				Channel:         channelID,
+				Content:         &data.Content,
+				Embeds:          &data.Embeds,
+				Components:      &components,
+				AllowedMentions: data.AllowedMentions,
+			})
+			if err == nil {
+				rs.sent = append(rs.sent, prev)
+				return m, nil
+			}
+		}
+		rs.Session.ChannelMessageDelete(prev.ChannelID, prev.ID)
+	}
+
+	m, err := rs.Session.ChannelMessageSendComplex(channelID, data)
+	if err != nil {
+		return nil, err
+	}
+	rs.sent = append(rs.sent, reply{m.ChannelID, m.ID})
+	return m, nil
+}
+
+func (rs *replySession) ChannelMessageDelete(channelID, messageID string) error {
+	err := rs.Session.ChannelMessageDelete(channelID, messageID)
+	if err == nil && messageID == rs.id {
+		rs.mu.Lock()
+		rs.deleted = true
+		rs.mu.Unlock()
+	}
+	return err
+}
+
+// finish deletes the previous replies that were not reused
+// and returns the replies sent.
+func (rs *replySession) finish() []reply {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for _, r := range rs.old {
+		rs.Session.ChannelMessageDelete(r.ChannelID, r.ID)
+	}
+	rs.old = nil
+	return rs.sent
+}
+
+func (b *Bot) messageDelete(s *dg.Session, m *dg.MessageDelete) {
+	b.deleteReplies(DiscordSession(s), m.ID, nil)
+}
+
+func (b *Bot) messageDeleteBulk(s *dg.Session, m *dg.MessageDeleteBulk) {
+	deleted := make(map[string]bool, len(m.Messages))
+	for _, id := range m.Messages {
+		deleted[id] = true
+	}
+	for _, id := range m.Messages {
+		b.deleteReplies(DiscordSession(s), id, deleted)
+	}
+}
+
+// deleteReplies deletes the replies to a message that was
+// deleted, skipping those that were deleted along with it.
+func (b *Bot) deleteReplies(s Session, id string, deleted map[string]bool) {
+	inv := b.replies.deleted(id)
+	if inv == nil {
+		return
+	}
+	b.deleteSent(s, inv.replies, deleted)
+}
+
+// deleteSent deletes replies, skipping those in deleted.
+func (b *Bot) deleteSent(s Session, replies []reply, deleted map[string]bool) {
+	for _, r := range replies {
+		if deleted[r.ID] {
+			continue
+		}
+		err := s.ChannelMessageDelete(r.ChannelID, r.ID)
+		if err != nil {
+			b.Logf("failed to delete reply to deleted message: %v", err)
+		}
+	}
+}
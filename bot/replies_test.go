@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/config"
+)
+
+// fakeSession records sends, edits, deletes and reactions.
+type fakeSession struct {
+	Session
+	mu    sync.Mutex
+	n     int
+	log   []string
+	edits []*dg.MessageEdit
+}
+
+// entries returns what has been recorded so far.
+func (s *fakeSession) entries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.log...)
+}
+
+func (s *fakeSession) ChannelMessageSendComplex(channelID string, data *dg.MessageSend) (*dg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n++
+	id := "m" + strconv.Itoa(s.n)
+	s.log = append(s.log, "send "+id+" "+data.Content)
+	return &dg.Message{ID: id, ChannelID: channelID}, nil
+}
+
+func (s *fakeSession) ChannelMessageEditComplex(m *dg.MessageEdit) (*dg.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.edits = append(s.edits, m)
+	if m.Content == nil {
+		s.log = append(s.log, fmt.Sprintf("edit %s %d components", m.ID, len(*m.Components)))
+		return &dg.Message{ID: m.ID, ChannelID: m.Channel}, nil
+	}
+	s.log = append(s.log, "edit "+m.ID+" "+*m.Content)
+	return &dg.Message{ID: m.ID, ChannelID: m.Channel}, nil
+}
+
+func (s *fakeSession) ChannelMessageDelete(channelID, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = append(s.log, "delete "+messageID)
+	return nil
+}
+
+func (s *fakeSession) MessageReactionAdd(channelID, messageID, emojiID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = append(s.log, "react "+messageID+" "+emojiID)
+	return nil
+}
+
+func (s *fakeSession) MessageReactionsRemoveAll(channelID, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = append(s.log, "unreact "+messageID)
+	return nil
+}
+
+func TestReplySession(t *testing.T) {
+	fs := new(fakeSession)
+
+	rs := &replySession{Session: fs}
+	rs.ChannelMessageSend("c", "one")
+	rs.ChannelMessageSend("c", "two")
+	rs.ChannelMessageSend("c", "three")
+	first := rs.finish()
+
+	// a rerun edits the first replies and deletes the rest
+	rs = &replySession{Session: fs, old: first}
+	rs.ChannelMessageSend("c", "uno")
+	rs.ChannelMessageSendComplex("c", &dg.MessageSend{Content: "file", Files: []*dg.File{{Name: "f"}}})
+	second := rs.finish()
+
+	want := []string{
+		"send m1 one", "send m2 two", "send m3 three",
+		"edit m1 uno",
+		"delete m2", "send m4 file",
+		"delete m3",
+	}
+	if !reflect.DeepEqual(fs.log, want) {
+		t.Errorf("got %q, want %q", fs.log, want)
+	}
+	if want := []reply{{"c", "m1"}, {"c", "m4"}}; !reflect.DeepEqual(second, want) {
+		t.Errorf("got replies %v, want %v", second, want)
+	}
+}
+
+func TestReplySessionComponents(t *testing.T) {
+	fs := new(fakeSession)
+	buttons := []dg.MessageComponent{dg.ActionsRow{Components: []dg.MessageComponent{dg.Button{Label: "Next", CustomID: "next"}}}}
+	none := &dg.MessageAllowedMentions{}
+
+	rs := &replySession{Session: fs}
+	rs.ChannelMessageSendComplex("c", &dg.MessageSend{Content: "page 1", Components: buttons})
+	first := rs.finish()
+
+	// a rerun keeps the buttons and the allowed mentions
+	rs = &replySession{Session: fs, old: first}
+	rs.ChannelMessageSendComplex("c", &dg.MessageSend{Content: "page 1", Components: buttons, AllowedMentions: none})
+	second := rs.finish()
+
+	// and a rerun without buttons clears them
+	rs = &replySession{Session: fs, old: second}
+	rs.ChannelMessageSend("c", "no pages")
+	rs.finish()
+
+	if len(fs.edits) != 2 {
+		t.Fatalf("got %d edits, want 2", len(fs.edits))
+	}
+	if e := fs.edits[0]; e.Components == nil || !reflect.DeepEqual(*e.Components, buttons) || e.AllowedMentions != none {
+		t.Errorf("rerun edited to %+v, want the buttons and allowed mentions", e)
+	}
+	if e := fs.edits[1]; e.Components == nil || len(*e.Components) != 0 {
+		t.Errorf("rerun edited to %+v, want the buttons cleared", e)
+	}
+}
+
+func TestReplyTracker(t *testing.T) {
+	rt := newReplyTracker(2)
+	rt.add(&invocation{id: "a"})
+	rt.add(&invocation{id: "b"})
+	rt.add(&invocation{id: "a", content: "again"})
+	rt.add(&invocation{id: "c"})
+
+	if rt.get("b") != nil {
+		t.Error("oldest invocation was not forgotten")
+	}
+	if inv := rt.get("a"); inv == nil || inv.content != "again" {
+		t.Errorf("got %+v, want the replaced invocation", inv)
+	}
+	if rt.remove("c") == nil || rt.get("c") != nil {
+		t.Error("invocation was not removed")
+	}
+}
+
+func TestDeleteReplies(t *testing.T) {
+	fs := new(fakeSession)
+	b := &Bot{replies: newReplyTracker(maxInvocations)}
+	b.replies.add(&invocation{id: "a", replies: []reply{{"c", "r1"}, {"c", "r2"}}})
+	b.replies.add(&invocation{id: "b", replies: []reply{{"c", "r3"}}})
+
+	// r2 was deleted along with a
+	b.deleteReplies(fs, "a", map[string]bool{"a": true, "r2": true})
+	b.deleteReplies(fs, "untracked", nil)
+
+	if want := []string{"delete r1"}; !reflect.DeepEqual(fs.log, want) {
+		t.Errorf("got %q, want %q", fs.log, want)
+	}
+	if b.replies.get("a") != nil || b.replies.get("b") == nil {
+		t.Error("wrong invocations forgotten")
+	}
+}
+
+func TestDeleteWhileRunning(t *testing.T) {
+	f, err := ioutil.TempFile("", "replies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"owner": "owner", "sigil": "!"}`)
+	f.Close()
+	cfg, err := config.New(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBot(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := new(fakeSession)
+	b.AddCommand(SimpleCommand("slow", func(s Session, m *dg.Message) {
+		s.ChannelMessageSend(m.ChannelID, "before")
+		// the invoking message is deleted while the command runs
+		b.deleteReplies(fs, m.ID, nil)
+		s.ChannelMessageSend(m.ChannelID, "after")
+	}, SimpleCommandInfo{}))
+
+	b.dispatchTracked(fs, "self", &dg.Message{ID: "msg", ChannelID: "c", Content: "!slow", Author: &dg.User{ID: "user"}}, nil)
+
+	want := []string{"send m1 before", "send m2 after", "delete m1", "delete m2"}
+	if got := fs.entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if b.replies.get("msg") != nil || len(b.replies.running) != 0 {
+		t.Error("deleted invocation still tracked")
+	}
+}
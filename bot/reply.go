@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+// Limits Discord puts on messages and embeds, in characters.
+const (
+	MaxMessageLength   = 2000
+	MaxEmbedTitle      = 256
+	MaxEmbedDesc       = 4096
+	MaxEmbedFields     = 25
+	MaxEmbedFieldName  = 256
+	MaxEmbedFieldValue = 1024
+	MaxEmbedFooter     = 2048
+	MaxEmbedAuthor     = 256
+	MaxEmbedTotal      = 6000
+)
+
+// maxReplyMessages is how many messages a reply may be split into
+// before it is sent as a file instead.
+const maxReplyMessages = 3
+
+// replyFileName is the name of the file long replies are sent as.
+const replyFileName = "reply.txt"
+
+// ReplyText replies to a message with text, as for Reply.
+func (b *Bot) ReplyText(s Session, m *dg.Message, text string) error {
+	return b.Reply(s, m, &dg.MessageSend{Content: text})
+}
+
+// Acknowledge deletes m, the invocation of a command that changes the bot
+// and has nothing to show for it. The bot cannot delete other users'
+// messages in direct messages, so there it replies with confirmation,
+// translated, instead; an empty confirmation sends nothing.
+func (b *Bot) Acknowledge(s Session, m *dg.Message, confirmation string) error {
+	if IsDM(m) {
+		if confirmation == "" {
+			return nil
+		}
+		return b.ReplyText(s, m, b.T(m.GuildID, confirmation))
+	}
+	return s.ChannelMessageDelete(m.ChannelID, m.ID)
+}
+
+// Reply sends a message to the channel of m, however long its content.
+// Content too long for one message is split between several, on line
+// or word boundaries, with code blocks closed at the end of one message
+// and reopened at the start of the next. If that would take more than a
+// few messages, the content is sent as a text file instead. Sessions that
+// implement TextSession are sent the content in one message. Embeds are
+// cut to fit Discord's limits. Embeds, files and components are sent
+// with the last message; AllowedMentions applies to them all.
+func (b *Bot) Reply(s Session, m *dg.Message, data *dg.MessageSend) error {
+	embeds := make([]*dg.MessageEmbed, len(data.Embeds))
+	for i, e := range data.Embeds {
+		embeds[i] = FitEmbed(e)
+	}
+
+	chunks, files := SplitText(data.Content, MaxMessageLength), data.Files
+	if _, ok := s.(TextSession); ok {
+		chunks = []string{data.Content}
+	} else if len(chunks) > maxReplyMessages {
+		chunks = []string{b.T(m.GuildID, "The reply was too long, so it is attached as a file.")}
+		files = append([]*dg.File{{
+			Name:        replyFileName,
+			ContentType: "text/plain",
+			Reader:      strings.NewReader(data.Content),
+		}}, files...)
+	}
+
+	for i, chunk := range chunks {
+		send := &dg.MessageSend{
+			Content:         chunk,
+			AllowedMentions: data.AllowedMentions,
+		}
+		if i == len(chunks)-1 {
+			send.Embeds = embeds
+			send.Files = files
+			send.Components = data.Components
+			send.Reference = data.Reference
+		}
+		_, err := s.ChannelMessageSendComplex(m.ChannelID, send)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SplitText splits text into pieces of at most n characters, on line
+// boundaries where it can and word boundaries where it must. A code
+// block split between pieces is closed at the end of one and reopened,
+// with its language, at the start of the next. Blank pieces are dropped,
+// but empty text is returned as a single empty piece.
+func SplitText(text string, n int) []string {
+	if utf8.RuneCountInString(text) <= n {
+		return []string{text}
+	}
+
+	const closing = "```"
+	var (
+		pieces []string
+		lines  []string // lines of the piece being built
+		size   int      // length of the piece being built
+		fresh  = true   // whether the piece holds no more than a reopened code block
+		inCode bool     // whether the text is in a code block
+		fence  string   // line reopening the code block, if it fits in a piece
+	)
+	add := func(line string) {
+		if len(lines) > 0 {
+			size++
+		}
+		lines = append(lines, line)
+		size += utf8.RuneCountInString(line)
+		fresh = false
+	}
+	flush := func(close bool) {
+		if close && fence != "" {
+			lines = append(lines, closing)
+		}
+		if piece := strings.Join(lines, "\n"); strings.TrimSpace(piece) != "" {
+			pieces = append(pieces, piece)
+		}
+		lines, size, fresh = nil, 0, true
+		if fence != "" {
+			lines, size = []string{fence}, utf8.RuneCountInString(fence)
+		}
+	}
+	// room returns the space left in the piece for a line, keeping
+	// enough to close the code block the text will be in after it
+	room := func(reopen bool) int {
+		r := n - size
+		if len(lines) > 0 {
+			r--
+		}
+		if reopen {
+			r -= 1 + len(closing)
+		}
+		return r
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		nextCode, next := inCode, fence
+		if strings.HasPrefix(strings.TrimSpace(line), closing) {
+			nextCode, next = !inCode, ""
+			// a code block is only reopened if its opening line, a line
+			// of at least one character and the closing fit in a piece
+			if nextCode && utf8.RuneCountInString(line)+3+len(closing) <= n {
+				next = line
+			}
+		}
+
+		if utf8.RuneCountInString(line) > room(next != "") && !fresh {
+			flush(true)
+		}
+		// a line too long for a piece of its own is split between words
+		for utf8.RuneCountInString(line) > room(next != "") {
+			var head string
+			head, line = splitWords(line, room(next != ""))
+			add(head)
+			flush(true)
+		}
+		add(line)
+		inCode, fence = nextCode, next
+	}
+	flush(false)
+	if len(pieces) == 0 {
+		pieces = []string{""}
+	}
+	return pieces
+}
+
+// splitWords splits s after at most n characters, at the last space
+// within them if there is one. The space is dropped.
+func splitWords(s string, n int) (head, tail string) {
+	if n < 1 {
+		n = 1
+	}
+	r := []rune(s)
+	if n >= len(r) {
+		n = len(r) - 1
+	}
+	for i := n; i > 0; i-- {
+		if r[i] == ' ' {
+			return string(r[:i]), string(r[i+1:])
+		}
+	}
+	return string(r[:n]), string(r[n:])
+}
+
+// truncate shortens s to at most n characters, ending it
+// with an ellipsis if it was cut.
+func truncate(s string, n int) string {
+	switch {
+	case utf8.RuneCountInString(s) <= n:
+		return s
+	case n < 1:
+		return ""
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// FitEmbed returns a copy of an embed cut to fit Discord's limits.
+// Over-long texts are truncated, fields past the limit on their number
+// are dropped, and fields are dropped from the end until the embed fits
+// within the limit on its total length.
+func FitEmbed(e *dg.MessageEmbed) *dg.MessageEmbed {
+	fit := *e
+	fit.Title = truncate(e.Title, MaxEmbedTitle)
+	fit.Description = truncate(e.Description, MaxEmbedDesc)
+	if e.Footer != nil {
+		footer := *e.Footer
+		footer.Text = truncate(footer.Text, MaxEmbedFooter)
+		fit.Footer = &footer
+	}
+	if e.Author != nil {
+		author := *e.Author
+		author.Name = truncate(author.Name, MaxEmbedAuthor)
+		fit.Author = &author
+	}
+
+	fit.Fields = nil
+	for i, f := range e.Fields {
+		if i == MaxEmbedFields {
+			break
+		}
+		field := *f
+		field.Name = truncate(field.Name, MaxEmbedFieldName)
+		field.Value = truncate(field.Value, MaxEmbedFieldValue)
+		fit.Fields = append(fit.Fields, &field)
+	}
+	for len(fit.Fields) > 0 && EmbedLength(&fit) > MaxEmbedTotal {
+		fit.Fields = fit.Fields[:len(fit.Fields)-1]
+	}
+	if over := EmbedLength(&fit) - MaxEmbedTotal; over > 0 {
+		fit.Description = truncate(fit.Description, utf8.RuneCountInString(fit.Description)-over)
+	}
+	return &fit
+}
+
+// EmbedLength returns the length of an embed's text as
+// Discord counts it toward the limit on its total.
+func EmbedLength(e *dg.MessageEmbed) int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	if e.Footer != nil {
+		n += utf8.RuneCountInString(e.Footer.Text)
+	}
+	if e.Author != nil {
+		n += utf8.RuneCountInString(e.Author.Name)
+	}
+	for _, f := range e.Fields {
+		n += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	return n
+}
@@ -0,0 +1,161 @@
+package bot_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want []string
+	}{
+		{"", 5, []string{""}},
+		{"short", 5, []string{"short"}},
+		{"aaa\nbbb\nccc", 7, []string{"aaa\nbbb", "ccc"}},
+		{"aaa\n\n\nbbb", 3, []string{"aaa", "bbb"}},
+		{"aaaa bbbb cccc", 9, []string{"aaaa bbbb", "cccc"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"日本語のテキスト", 4, []string{"日本語の", "テキスト"}},
+		{"```go\nx := 1\ny := 2\n```", 16, []string{"```go\nx := 1\n```", "```go\ny := 2\n```"}},
+		{"before\n```\ncode\n```\nafter", 15, []string{"before\n```\n```", "```\ncode\n```", "after"}},
+	}
+	for _, tt := range tests {
+		if got := bot.SplitText(tt.text, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitText(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTextLimits(t *testing.T) {
+	var lines []string
+	for i := 0; i < 200; i++ {
+		switch i % 40 {
+		case 10, 30:
+			lines = append(lines, "```py")
+		case 20, 39:
+			lines = append(lines, "```")
+		default:
+			lines = append(lines, strings.Repeat("word ", i%17)+strings.Repeat("x", i%90))
+		}
+	}
+	text := strings.Join(lines, "\n")
+
+	pieces := bot.SplitText(text, 100)
+	for i, p := range pieces {
+		if utf8.RuneCountInString(p) > 100 {
+			t.Errorf("piece %d is %d long", i, utf8.RuneCountInString(p))
+		}
+		if strings.Count(p, "```")%2 != 0 {
+			t.Errorf("piece %d has an unclosed code block: %q", i, p)
+		}
+	}
+}
+
+func TestSplitTextLongFence(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+	}{
+		{"```" + strings.Repeat("a", 2000) + "\nb\n" + strings.Repeat("c", 30) + "\n" + strings.Repeat("d", 1990), 2000},
+		{"```go\nx\n```", 6},
+		{"```go\nxyz", 5},
+		{"a b", 1},
+	}
+	for _, tt := range tests {
+		for i, p := range bot.SplitText(tt.text, tt.n) {
+			if n := utf8.RuneCountInString(p); n > tt.n {
+				t.Errorf("SplitText(%.20q, %d): piece %d is %d long", tt.text, tt.n, i, n)
+			}
+		}
+	}
+}
+
+func TestReply(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	m := bottest.Message("c", "user", "")
+
+	s := bottest.NewSession()
+	line := strings.Repeat("x", 999) + "\n"
+	err := b.Reply(s, m, &dg.MessageSend{
+		Content:         strings.Repeat(line, 3),
+		Embeds:          []*dg.MessageEmbed{{Title: strings.Repeat("t", 300)}},
+		AllowedMentions: &dg.MessageAllowedMentions{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Sent) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(s.Sent))
+	}
+	if got := s.Contents(); got[0] != strings.Repeat(line, 2)[:len(line)*2-1] || got[1] != line {
+		t.Errorf("split into %d and %d characters", len(got[0]), len(got[1]))
+	}
+	if len(s.Sent[0].Embeds) != 0 || len(s.Sent[1].Embeds) != 1 {
+		t.Error("embed not sent with the last message")
+	} else if n := utf8.RuneCountInString(s.Sent[1].Embeds[0].Title); n != bot.MaxEmbedTitle {
+		t.Errorf("got a title %d long, want %d", n, bot.MaxEmbedTitle)
+	}
+
+	s = bottest.NewSession()
+	long := strings.Repeat(line, 10)
+	if err := b.ReplyText(s, m, long); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Contents(), []string{"The reply was too long, so it is attached as a file."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := string(s.Files["reply.txt"]); got != long {
+		t.Errorf("attached %d characters, want %d", len(got), len(long))
+	}
+
+	ts := textSession{bottest.NewSession()}
+	if err := b.ReplyText(ts, m, long); err != nil {
+		t.Fatal(err)
+	}
+	if got := ts.Contents(); !reflect.DeepEqual(got, []string{long}) || len(ts.Files) != 0 {
+		t.Errorf("sent %d messages and %d files, want the text in one message", len(got), len(ts.Files))
+	}
+}
+
+// textSession is a session that shows text of any length.
+type textSession struct {
+	*bottest.Session
+}
+
+func (textSession) PlainText() {}
+
+func TestFitEmbed(t *testing.T) {
+	e := &dg.MessageEmbed{
+		Title:  "title",
+		Footer: &dg.MessageEmbedFooter{Text: strings.Repeat("f", 3000)},
+	}
+	for i := 0; i < 30; i++ {
+		e.Fields = append(e.Fields, &dg.MessageEmbedField{Name: "name", Value: strings.Repeat("v", 2000)})
+	}
+
+	fit := bot.FitEmbed(e)
+	if len(e.Fields) != 30 || len(e.Fields[0].Value) != 2000 {
+		t.Error("the embed fitted was changed")
+	}
+	if n := bot.EmbedLength(fit); n > bot.MaxEmbedTotal {
+		t.Errorf("got an embed %d long", n)
+	}
+	if n := utf8.RuneCountInString(fit.Footer.Text); n != bot.MaxEmbedFooter {
+		t.Errorf("got a footer %d long, want %d", n, bot.MaxEmbedFooter)
+	}
+	// 5 + 2048 + 3 * (4 + 1024) = 5137, and a fourth field would not fit
+	if len(fit.Fields) != 3 {
+		t.Errorf("got %d fields, want 3", len(fit.Fields))
+	}
+	if v := fit.Fields[0].Value; utf8.RuneCountInString(v) != bot.MaxEmbedFieldValue || !strings.HasSuffix(v, "…") {
+		t.Errorf("field value not truncated: %d long", utf8.RuneCountInString(v))
+	}
+}
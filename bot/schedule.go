@@ -0,0 +1,549 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/cron"
+	"github.com/pkg/errors"
+)
+
+// JobFunc does the work of a scheduled job, given the job's data.
+// The context is cancelled when the job times out, is cancelled,
+// or the plugin that handles it is unloaded.
+type JobFunc func(ctx context.Context, data json.RawMessage) error
+
+// Job is work run by a handler at scheduled times.
+// Exactly one of Cron, Every and At must be set.
+// Jobs are persisted in the store, so they survive restarts
+// as long as their handler is registered again.
+type Job struct {
+	// Name identifies the job. Scheduling a job with the
+	// same name as another replaces the other job.
+	Name string `json:"name"`
+
+	// Handler is the name of the JobFunc that runs the job,
+	// as registered with HandleJob, and Data is passed to it.
+	Handler string          `json:"handler"`
+	Data    json.RawMessage `json:"data,omitempty"`
+
+	Cron  string        `json:"cron,omitempty"`  // run at times matching a cron expression
+	Every time.Duration `json:"every,omitempty"` // run at a fixed interval
+	At    time.Time     `json:"at"`              // run once at a time
+
+	// Jitter is the longest a run is randomly delayed by,
+	// to spread out jobs scheduled for the same time.
+	Jitter time.Duration `json:"jitter,omitempty"`
+
+	// Timeout is how long a run may take before its
+	// context is cancelled. Zero means no limit.
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// JobStatus describes a scheduled job.
+type JobStatus struct {
+	Job
+	Plugin  string    // plugin handling the job
+	Next    time.Time // when the job runs next, before jitter
+	Last    time.Time // when the job last ran
+	Err     string    // error from the last run
+	Running bool
+}
+
+// jobRecord is a job as persisted in the store.
+type jobRecord struct {
+	Job
+	Next time.Time `json:"next"`
+	Last time.Time `json:"last"`
+	Err  string    `json:"error,omitempty"`
+}
+
+type jobHandler struct {
+	fn     JobFunc
+	plugin string
+}
+
+type scheduledJob struct {
+	jobRecord
+	cron   *cron.Schedule
+	timer  *time.Timer
+	cancel context.CancelFunc // cancels the running run, if any
+	due    bool               // whether a one-shot job came due while running
+}
+
+// jobPrefix is the prefix of the store keys of jobs.
+const jobPrefix = "jobs/"
+
+// scheduler runs the jobs of a bot.
+type scheduler struct {
+	mu       sync.Mutex
+	handlers map[string]jobHandler
+	jobs     map[string]*scheduledJob
+	started  bool
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		handlers: make(map[string]jobHandler),
+		jobs:     make(map[string]*scheduledJob),
+	}
+}
+
+func (j *scheduledJob) status(plugin string) JobStatus {
+	return JobStatus{
+		Job:     j.Job,
+		Plugin:  plugin,
+		Next:    j.Next,
+		Last:    j.Last,
+		Err:     j.Err,
+		Running: j.cancel != nil,
+	}
+}
+
+// next returns when a job should next run after its last
+// scheduled time, or the zero time if it should not.
+func (j *scheduledJob) next(now time.Time) time.Time {
+	switch {
+	case j.cron != nil:
+		return j.cron.Next(now)
+	case j.Every > 0:
+		next := j.Next.Add(j.Every)
+		if next.Before(now) {
+			next = now.Add(j.Every)
+		}
+		return next
+	default:
+		return time.Time{}
+	}
+}
+
+func (j *Job) validate() (*cron.Schedule, error) {
+	if j.Name == "" {
+		return nil, errors.New("job has no name")
+	}
+	if j.Handler == "" {
+		return nil, errors.Errorf("job %q has no handler", j.Name)
+	}
+
+	n := 0
+	if j.Cron != "" {
+		n++
+	}
+	if j.Every != 0 {
+		n++
+	}
+	if !j.At.IsZero() {
+		n++
+	}
+	if n != 1 {
+		return nil, errors.Errorf("job %q must have exactly one of a cron expression, an interval or a time", j.Name)
+	}
+	if j.Every < 0 || j.Jitter < 0 || j.Timeout < 0 {
+		return nil, errors.Errorf("job %q has a negative duration", j.Name)
+	}
+
+	if j.Cron == "" {
+		return nil, nil
+	}
+	return cron.Parse(j.Cron)
+}
+
+// sameSchedule reports whether two jobs run at the same times.
+func sameSchedule(a, b Job) bool {
+	return a.Cron == b.Cron && a.Every == b.Every && a.At.Equal(b.At)
+}
+
+// HandleJob registers the function that runs jobs with a handler name.
+// Jobs for the handler saved before a restart are scheduled again.
+// If called while loading a plugin, the handler and its jobs are
+// removed when the plugin is unloaded.
+func (b *Bot) HandleJob(handler string, fn JobFunc) {
+	b.commandsMu.RLock()
+	plugin := b.loading
+	b.commandsMu.RUnlock()
+
+	// restore saved jobs
+	var saved []jobRecord
+	for _, key := range b.Store.Keys(jobPrefix) {
+		var rec jobRecord
+		err := b.Store.Get(key, &rec)
+		if err != nil {
+			b.Logf("[jobs] %s: %v", key, err)
+			continue
+		}
+		if rec.Handler == handler {
+			saved = append(saved, rec)
+		}
+	}
+
+	sc := b.scheduler
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.handlers[handler] = jobHandler{fn, plugin}
+	for _, rec := range saved {
+		if _, ok := sc.jobs[rec.Name]; ok {
+			continue
+		}
+		schedule, err := rec.validate()
+		if err != nil {
+			b.Logf("[jobs] %v", err)
+			continue
+		}
+		j := &scheduledJob{jobRecord: rec, cron: schedule}
+		sc.jobs[rec.Name] = j
+		b.arm(j)
+	}
+}
+
+// ScheduleJob schedules a job, replacing any job with the same name.
+// If a job with the same name and schedule was saved before a restart,
+// it keeps its next run time, so restarting does not delay or skip runs.
+// Jobs whose handler is not registered wait until it is.
+func (b *Bot) ScheduleJob(job Job) error {
+	schedule, err := job.validate()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	j := &scheduledJob{jobRecord: jobRecord{Job: job}, cron: schedule}
+	var saved jobRecord
+	if b.Store.Exists(jobPrefix+job.Name) && b.Store.Get(jobPrefix+job.Name, &saved) == nil && sameSchedule(saved.Job, job) {
+		j.Next, j.Last, j.Err = saved.Next, saved.Last, saved.Err
+	}
+	if j.Next.IsZero() {
+		switch {
+		case schedule != nil:
+			j.Next = schedule.Next(now)
+		case job.Every > 0:
+			j.Next = now.Add(job.Every)
+		default:
+			j.Next = job.At
+		}
+	}
+	if j.Next.IsZero() {
+		return errors.Errorf("job %q never runs", job.Name)
+	}
+
+	sc := b.scheduler
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if old, ok := sc.jobs[job.Name]; ok {
+		old.stop()
+	}
+	sc.jobs[job.Name] = j
+	err = b.saveJob(j)
+	if err != nil {
+		return err
+	}
+	b.arm(j)
+	return nil
+}
+
+// CancelJob stops and forgets a job, cancelling it if it is
+// running, and reports whether there was a job with the name.
+func (b *Bot) CancelJob(name string) bool {
+	sc := b.scheduler
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	j, ok := sc.jobs[name]
+	if ok {
+		j.stop()
+		delete(sc.jobs, name)
+	}
+	if b.Store.Exists(jobPrefix + name) {
+		ok = true
+		err := b.Store.Delete(jobPrefix + name)
+		if err != nil {
+			b.Logf("[jobs] %v", err)
+		}
+	}
+	return ok
+}
+
+// RunJob runs a job now, without changing when it next runs.
+func (b *Bot) RunJob(name string) error {
+	sc := b.scheduler
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	j, ok := sc.jobs[name]
+	if !ok {
+		return errors.Errorf("job %q not found", name)
+	}
+	if _, ok := sc.handlers[j.Handler]; !ok {
+		return errors.Errorf("job %q has no handler %q", name, j.Handler)
+	}
+	if j.cancel != nil {
+		return errors.Errorf("job %q is already running", name)
+	}
+	b.run(j, false)
+	return nil
+}
+
+// Jobs returns the status of every scheduled job, sorted by name.
+func (b *Bot) Jobs() []JobStatus {
+	sc := b.scheduler
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	jobs := make([]JobStatus, 0, len(sc.jobs))
+	for _, j := range sc.jobs {
+		jobs = append(jobs, j.status(sc.handlers[j.Handler].plugin))
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].Name < jobs[k].Name })
+	return jobs
+}
+
+// startJobs starts running jobs when they are due.
+func (b *Bot) startJobs() {
+	sc := b.scheduler
+	sc.mu.Lock()
+	sc.started = true
+	for _, j := range sc.jobs {
+		b.arm(j)
+	}
+	sc.mu.Unlock()
+	b.Defer(b.stopJobs)
+}
+
+// stopJobs stops running jobs, cancelling any that are running.
+func (b *Bot) stopJobs() {
+	sc := b.scheduler
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.started = false
+	for _, j := range sc.jobs {
+		j.stop()
+	}
+}
+
+// unloadJobs removes the job handlers added by a plugin
+// and stops their jobs. The jobs stay saved, so they are
+// scheduled again if the plugin is loaded again.
+func (b *Bot) unloadJobs(plugin string) {
+	sc := b.scheduler
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	for name, h := range sc.handlers {
+		if h.plugin != plugin {
+			continue
+		}
+		delete(sc.handlers, name)
+		for _, j := range sc.jobs {
+			if j.Handler == name {
+				j.stop()
+				delete(sc.jobs, j.Name)
+			}
+		}
+	}
+}
+
+// stop stops a job's timer and cancels it if it is running.
+func (j *scheduledJob) stop() {
+	if j.timer != nil {
+		j.timer.Stop()
+		j.timer = nil
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+}
+
+// arm sets a job's timer to run it when it is next due.
+// Jobs wait until the scheduler is started and their handler is
+// registered. The scheduler's lock must be held.
+func (b *Bot) arm(j *scheduledJob) {
+	sc := b.scheduler
+	if _, ok := sc.handlers[j.Handler]; !ok || !sc.started || j.timer != nil || j.Next.IsZero() {
+		return
+	}
+
+	delay := time.Until(j.Next)
+	if j.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(j.Jitter)))
+	}
+	j.timer = time.AfterFunc(delay, func() {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+		if sc.jobs[j.Name] != j || j.timer == nil {
+			return // replaced or stopped
+		}
+		j.timer = nil
+
+		switch {
+		case j.cancel != nil && j.cron == nil && j.Every == 0:
+			// skipping would lose the only run
+			b.Logf("[jobs] %s: waiting for the last run to finish", j.Name)
+			j.due = true
+			return
+		case j.cancel != nil:
+			b.Logf("[jobs] %s: skipped a run because the last is still running", j.Name)
+		default:
+			b.run(j, true)
+		}
+
+		j.Next = j.next(time.Now())
+		if j.Next.IsZero() {
+			// a one-shot job is forgotten once it has run
+			return
+		}
+		err := b.saveJob(j)
+		if err != nil {
+			b.Logf("[jobs] %v", err)
+		}
+		b.arm(j)
+	})
+}
+
+// run starts a run of a job. If scheduled, the job is forgotten after
+// the run if it does not run again. The scheduler's lock must be held.
+func (b *Bot) run(j *scheduledJob, scheduled bool) {
+	sc := b.scheduler
+	h := sc.handlers[j.Handler]
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if j.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), j.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	j.cancel = cancel
+	j.Last = time.Now()
+	oneShot := scheduled && j.cron == nil && j.Every == 0
+
+	go func() {
+		err := b.runJobFunc(ctx, j, h)
+		cancel()
+
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+		j.cancel = nil
+		j.Err = ""
+		if err != nil {
+			j.Err = err.Error()
+			b.Logf("[jobs] %s: %v", j.Name, err)
+		}
+		if sc.jobs[j.Name] != j {
+			return // replaced or cancelled while running
+		}
+		if j.due {
+			j.due = false
+			if _, ok := sc.handlers[j.Handler]; ok && sc.started {
+				b.run(j, true)
+				return
+			}
+		}
+		if oneShot {
+			delete(sc.jobs, j.Name)
+			err = b.Store.Delete(jobPrefix + j.Name)
+		} else {
+			err = b.saveJob(j)
+		}
+		if err != nil {
+			b.Logf("[jobs] %v", err)
+		}
+	}()
+}
+
+// runJobFunc runs a job's handler, turning a panic into an error.
+func (b *Bot) runJobFunc(ctx context.Context, j *scheduledJob, h jobHandler) (err error) {
+	if b.safely(fmt.Sprintf("job %q", j.Name), h.plugin, func() { err = h.fn(ctx, j.Data) }) {
+		return errors.New("panicked")
+	}
+	return err
+}
+
+// saveJob persists a job. The scheduler's lock must be held.
+func (b *Bot) saveJob(j *scheduledJob) error {
+	return errors.Wrapf(b.Store.Set(jobPrefix+j.Name, j.jobRecord), "save job %q", j.Name)
+}
+
+type jobsCommand struct {
+	*Bot
+}
+
+func (c jobsCommand) Name() string {
+	return "jobs"
+}
+
+func (c jobsCommand) Comment() string {
+	return "list and run scheduled jobs"
+}
+
+func (c jobsCommand) Usage() []string {
+	return []string{"jobs", "jobs run <name>"}
+}
+
+func (c jobsCommand) Description() string {
+	return "List the scheduled jobs with when they last ran and run next, or run a job now."
+}
+
+func (c jobsCommand) Owner() {}
+
+func (c jobsCommand) Execute(s Session, m *dg.Message) {
+	var text string
+	if strings.HasPrefix(m.Content, "run ") {
+		name := strings.TrimSpace(m.Content[len("run "):])
+		text = c.T(m.GuildID, "Running job %q.", name)
+		if err := c.RunJob(name); err != nil {
+			text = err.Error()
+		}
+	} else {
+		text = c.list(m.GuildID)
+	}
+
+	_, err := s.ChannelMessageSend(m.ChannelID, text)
+	if err != nil {
+		c.Log("[jobs]", err)
+	}
+}
+
+func (c jobsCommand) list(guildID string) string {
+	jobs := c.Jobs()
+	if len(jobs) == 0 {
+		return c.T(guildID, "No jobs are scheduled.")
+	}
+
+	never := c.T(guildID, "never")
+	when := func(t time.Time) string {
+		if t.IsZero() {
+			return never
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+
+	lines := make([]string, len(jobs))
+	for i, j := range jobs {
+		schedule := j.Cron
+		switch {
+		case j.Every > 0:
+			schedule = c.T(guildID, "every %v", j.Every)
+		case !j.At.IsZero():
+			schedule = c.T(guildID, "once")
+		}
+		line := fmt.Sprintf("%s (%s): %s", j.Name, schedule, c.T(guildID, "next %s, last %s", when(j.Next), when(j.Last)))
+		if j.Running {
+			line += ", " + c.T(guildID, "running")
+		}
+		if j.Err != "" {
+			line += ", " + c.T(guildID, "error: %s", j.Err)
+		}
+		lines[i] = line
+	}
+	return "```\n" + strings.Join(lines, "\n") + "\n```"
+}
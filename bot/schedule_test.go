@@ -0,0 +1,219 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/njhanley/stoopid/store"
+)
+
+func newJobBot(st *store.Store) *Bot {
+	if st == nil {
+		st, _ = store.New("")
+	}
+	return &Bot{
+		Store:          st,
+		commands:       make(map[string]Command),
+		commandPlugins: make(map[string]string),
+		plugins:        make(map[string]Plugin),
+		scheduler:      newScheduler(),
+		events:         newEventBus(),
+		logger:         log.New(ioutil.Discard, "", 0),
+	}
+}
+
+// wait waits for a value from c or fails the test after a second.
+func wait(t *testing.T, c <-chan string) string {
+	t.Helper()
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+		return ""
+	}
+}
+
+func TestScheduleJob(t *testing.T) {
+	b := newJobBot(nil)
+	defer b.Stop()
+	runs := make(chan string, 10)
+	b.HandleJob("echo", func(_ context.Context, data json.RawMessage) error {
+		var s string
+		json.Unmarshal(data, &s)
+		runs <- s
+		return nil
+	})
+	b.startJobs()
+
+	err := b.ScheduleJob(Job{Name: "often", Handler: "echo", Data: json.RawMessage(`"often"`), Every: 10 * time.Millisecond, Jitter: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.ScheduleJob(Job{Name: "once", Handler: "echo", Data: json.RawMessage(`"once"`), At: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]int)
+	for seen["often"] < 2 || seen["once"] < 1 {
+		seen[wait(t, runs)]++
+	}
+	if seen["once"] != 1 {
+		t.Errorf("one-shot job ran %d times", seen["once"])
+	}
+	b.CancelJob("often")
+
+	// give the one-shot job's run time to finish
+	time.Sleep(20 * time.Millisecond)
+	if jobs := b.Jobs(); len(jobs) != 0 {
+		t.Errorf("got jobs %+v after cancelling and running once, want none", jobs)
+	}
+	if keys := b.Store.Keys(jobPrefix); len(keys) != 0 {
+		t.Errorf("got saved jobs %q, want none", keys)
+	}
+}
+
+func TestScheduleJobErrors(t *testing.T) {
+	b := newJobBot(nil)
+	defer b.Stop()
+	for _, job := range []Job{
+		{Handler: "h", Every: time.Minute},
+		{Name: "j", Every: time.Minute},
+		{Name: "j", Handler: "h"},
+		{Name: "j", Handler: "h", Every: time.Minute, Cron: "@daily"},
+		{Name: "j", Handler: "h", Cron: "61 * * * *"},
+		{Name: "j", Handler: "h", Every: -time.Minute},
+	} {
+		if err := b.ScheduleJob(job); err == nil {
+			t.Errorf("%+v scheduled without error", job)
+		}
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	b := newJobBot(nil)
+	defer b.Stop()
+	errs := make(chan string, 1)
+	b.HandleJob("slow", func(ctx context.Context, _ json.RawMessage) error {
+		<-ctx.Done()
+		errs <- ctx.Err().Error()
+		return ctx.Err()
+	})
+	b.startJobs()
+
+	err := b.ScheduleJob(Job{Name: "slow", Handler: "slow", Cron: "@yearly", Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.RunJob("slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wait(t, errs); got != context.DeadlineExceeded.Error() {
+		t.Errorf("got %q, want a deadline error", got)
+	}
+}
+
+func TestJobsPersisted(t *testing.T) {
+	st, _ := store.New("")
+	b := newJobBot(st)
+	defer b.Stop()
+	at := time.Now().Add(time.Hour).Round(0)
+	err := b.ScheduleJob(Job{Name: "later", Handler: "remind", At: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a restarted bot schedules the job once its handler is registered
+	b = newJobBot(st)
+	defer b.Stop()
+	if jobs := b.Jobs(); len(jobs) != 0 {
+		t.Errorf("got jobs %+v without a handler, want none", jobs)
+	}
+	b.HandleJob("remind", func(context.Context, json.RawMessage) error { return nil })
+	jobs := b.Jobs()
+	if len(jobs) != 1 || jobs[0].Name != "later" || !jobs[0].Next.Equal(at) {
+		t.Errorf("got jobs %+v, want the saved job", jobs)
+	}
+}
+
+func TestUnloadPluginCancelsJobs(t *testing.T) {
+	b := newJobBot(nil)
+	defer b.Stop()
+	started, cancelled := make(chan string, 1), make(chan string, 1)
+	err := b.AddPlugin(SimplePlugin("poller", func(b *Bot) error {
+		b.HandleJob("poll", func(ctx context.Context, _ json.RawMessage) error {
+			started <- "started"
+			<-ctx.Done()
+			cancelled <- "cancelled"
+			return ctx.Err()
+		})
+		return b.ScheduleJob(Job{Name: "poll", Handler: "poll", Every: time.Hour})
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.startJobs()
+
+	err = b.RunJob("poll")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(t, started)
+	err = b.UnloadPlugin("poller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(t, cancelled)
+
+	if jobs := b.Jobs(); len(jobs) != 0 {
+		t.Errorf("got jobs %+v after unloading, want none", jobs)
+	}
+	if !b.Store.Exists(jobPrefix + "poll") {
+		t.Error("unloading forgot the saved job")
+	}
+	if b.GetPlugin("poller") != nil {
+		t.Error("plugin is still loaded")
+	}
+}
+
+func TestOneShotDueWhileRunning(t *testing.T) {
+	b := newJobBot(nil)
+	defer b.Stop()
+	runs := make(chan string, 10)
+	release := make(chan struct{})
+	b.HandleJob("slow", func(context.Context, json.RawMessage) error {
+		runs <- "run"
+		<-release
+		return nil
+	})
+	b.startJobs()
+
+	err := b.ScheduleJob(Job{Name: "once", Handler: "slow", At: time.Now().Add(50 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.RunJob("once"); err != nil {
+		t.Fatal(err)
+	}
+	wait(t, runs)
+
+	// the job comes due while it is run by hand
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wait(t, runs)
+
+	for start := time.Now(); len(b.Jobs()) != 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("one-shot job not forgotten after its run")
+		}
+	}
+	if b.Store.Exists(jobPrefix + "once") {
+		t.Error("one-shot job still saved after its run")
+	}
+}
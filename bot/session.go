@@ -0,0 +1,130 @@
+package bot
+
+import (
+	dg "github.com/bwmarrin/discordgo"
+)
+
+// Session is the interface commands use to talk to Discord.
+// The method names and signatures mirror those of *discordgo.Session
+// where one exists.
+type Session interface {
+	ChannelMessageSend(channelID, content string) (*dg.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *dg.MessageEmbed) (*dg.Message, error)
+	ChannelMessageSendComplex(channelID string, data *dg.MessageSend) (*dg.Message, error)
+	ChannelMessageEditComplex(m *dg.MessageEdit) (*dg.Message, error)
+	ChannelMessageDelete(channelID, messageID string) error
+
+	// MessageReactionAdd reacts to a message with an emoji, which is
+	// a Unicode emoji or a custom emoji in the form name:id.
+	MessageReactionAdd(channelID, messageID, emojiID string) error
+	// MessageReactionsRemoveAll removes every reaction from a message.
+	MessageReactionsRemoveAll(channelID, messageID string) error
+
+	Channel(channelID string) (*dg.Channel, error)
+	Guild(guildID string) (*dg.Guild, error)
+	GuildMember(guildID, userID string) (*dg.Member, error)
+	GuildMemberNickname(guildID, userID, nickname string) error
+	// UserChannelPermissions returns a user's permissions in a channel.
+	// The user ID "@me" refers to the bot.
+	UserChannelPermissions(userID, channelID string) (int64, error)
+
+	// SetAvatar changes the bot's avatar to a data URI.
+	SetAvatar(avatar string) error
+	// SetStatus changes the game shown in the bot's status.
+	SetStatus(game string) error
+}
+
+// TextSession is implemented by sessions for transports that show text
+// of any length themselves, such as the console and IRC, where an attached
+// file would show only as its name. Reply sends long text to them as is.
+type TextSession interface {
+	Session
+	PlainText()
+}
+
+// DiscordSession adapts a discordgo session to the Session interface.
+// Channels and members are looked up in the session's state
+// before falling back to the REST API.
+func DiscordSession(s *dg.Session) Session {
+	return discordSession{s}
+}
+
+type discordSession struct {
+	s *dg.Session
+}
+
+func (s discordSession) ChannelMessageSend(channelID, content string) (*dg.Message, error) {
+	return s.s.ChannelMessageSend(channelID, content)
+}
+
+func (s discordSession) ChannelMessageSendEmbed(channelID string, embed *dg.MessageEmbed) (*dg.Message, error) {
+	return s.s.ChannelMessageSendEmbed(channelID, embed)
+}
+
+func (s discordSession) ChannelMessageSendComplex(channelID string, data *dg.MessageSend) (*dg.Message, error) {
+	return s.s.ChannelMessageSendComplex(channelID, data)
+}
+
+func (s discordSession) ChannelMessageEditComplex(m *dg.MessageEdit) (*dg.Message, error) {
+	return s.s.ChannelMessageEditComplex(m)
+}
+
+func (s discordSession) ChannelMessageDelete(channelID, messageID string) error {
+	return s.s.ChannelMessageDelete(channelID, messageID)
+}
+
+func (s discordSession) MessageReactionAdd(channelID, messageID, emojiID string) error {
+	return s.s.MessageReactionAdd(channelID, messageID, emojiID)
+}
+
+func (s discordSession) MessageReactionsRemoveAll(channelID, messageID string) error {
+	return s.s.MessageReactionsRemoveAll(channelID, messageID)
+}
+
+func (s discordSession) Channel(channelID string) (*dg.Channel, error) {
+	ch, err := s.s.State.Channel(channelID)
+	if err == nil {
+		return ch, nil
+	}
+	return s.s.Channel(channelID)
+}
+
+func (s discordSession) Guild(guildID string) (*dg.Guild, error) {
+	g, err := s.s.State.Guild(guildID)
+	if err == nil {
+		return g, nil
+	}
+	return s.s.Guild(guildID)
+}
+
+func (s discordSession) GuildMember(guildID, userID string) (*dg.Member, error) {
+	mem, err := s.s.State.Member(guildID, userID)
+	if err == nil {
+		return mem, nil
+	}
+	return s.s.GuildMember(guildID, userID)
+}
+
+func (s discordSession) GuildMemberNickname(guildID, userID, nickname string) error {
+	return s.s.GuildMemberNickname(guildID, userID, nickname)
+}
+
+func (s discordSession) UserChannelPermissions(userID, channelID string) (int64, error) {
+	if userID == "@me" {
+		userID = s.s.State.User.ID
+	}
+	perms, err := s.s.State.UserChannelPermissions(userID, channelID)
+	if err == nil {
+		return perms, nil
+	}
+	return s.s.UserChannelPermissions(userID, channelID)
+}
+
+func (s discordSession) SetAvatar(avatar string) error {
+	_, err := s.s.UserUpdate("", avatar, "")
+	return err
+}
+
+func (s discordSession) SetStatus(game string) error {
+	return s.s.UpdateGameStatus(0, game)
+}
@@ -0,0 +1,276 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// identifyInterval is how long Discord requires between
+// shards identifying in the same concurrency bucket.
+const identifyInterval = 5 * time.Second
+
+// HTTPClient is the HTTP client shared by the sessions of every bot in
+// the process, and by plugins for their own requests.
+var HTTPClient = &http.Client{Timeout: 20 * time.Second}
+
+// ShardStatus describes the connection of a shard.
+type ShardStatus struct {
+	ID        int
+	Connected bool
+	Since     time.Time // when the shard connected or disconnected
+	Guilds    int
+	Latency   time.Duration
+}
+
+// loadShardCfg reads the "shards" config key, which is either
+// a number of shards or "auto" to use the number Discord recommends.
+func (b *Bot) loadShardCfg() error {
+	b.shardCount = 1
+	if !b.Config.Exists("shards") {
+		return nil
+	}
+
+	var auto string
+	if b.Config.Get("shards", &auto) == nil && auto == "auto" {
+		b.shardCount = 0
+		return nil
+	}
+	err := b.Config.Get("shards", &b.shardCount)
+	if err != nil {
+		return err
+	}
+	if b.shardCount < 1 {
+		return errors.Errorf("shards must be at least 1 or \"auto\", not %d", b.shardCount)
+	}
+	return nil
+}
+
+func (b *Bot) newSession() (*dg.Session, error) {
+	s, err := dg.New("Bot " + b.token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create session")
+	}
+	s.Client = HTTPClient
+	s.Identify.Intents = dg.IntentsGuilds |
+		dg.IntentsGuildMessages |
+		dg.IntentsGuildMessageReactions |
+		dg.IntentsDirectMessages |
+		dg.IntentsDirectMessageReactions |
+		dg.IntentsMessageContent
+	return s, nil
+}
+
+// eventHandler is an event handler added to every shard.
+type eventHandler struct {
+	fn     interface{}
+	plugin string   // plugin that added the handler, if any
+	remove []func() // remove the handler from each shard
+}
+
+// AddHandler adds an event handler, as for discordgo's AddHandler,
+// to every shard, including shards created after it is added.
+// If called while loading a plugin, the handler is removed
+// when the plugin is unloaded.
+func (b *Bot) AddHandler(handler interface{}) {
+	b.commandsMu.RLock()
+	h := &eventHandler{plugin: b.loading}
+	b.commandsMu.RUnlock()
+	h.fn = b.safeHandler(handler, h.plugin)
+
+	b.shardsMu.Lock()
+	defer b.shardsMu.Unlock()
+	b.handlers = append(b.handlers, h)
+	for _, s := range b.shards {
+		h.remove = append(h.remove, s.AddHandler(h.fn))
+	}
+}
+
+// removeHandlers removes the event handlers added by a plugin.
+func (b *Bot) removeHandlers(plugin string) {
+	b.shardsMu.Lock()
+	defer b.shardsMu.Unlock()
+	kept := b.handlers[:0]
+	for _, h := range b.handlers {
+		if h.plugin != plugin {
+			kept = append(kept, h)
+			continue
+		}
+		for _, remove := range h.remove {
+			remove()
+		}
+	}
+	b.handlers = kept
+}
+
+// Shards returns the session of every shard, ordered by shard ID.
+func (b *Bot) Shards() []*dg.Session {
+	b.shardsMu.RLock()
+	defer b.shardsMu.RUnlock()
+	return append([]*dg.Session(nil), b.shards...)
+}
+
+// ShardFor returns the session of the shard receiving a guild's events.
+// Direct messages are received by the first shard.
+func (b *Bot) ShardFor(guildID string) *dg.Session {
+	b.shardsMu.RLock()
+	defer b.shardsMu.RUnlock()
+	id, err := strconv.ParseUint(guildID, 10, 64)
+	if err != nil {
+		return b.shards[0]
+	}
+	return b.shards[(id>>22)%uint64(len(b.shards))]
+}
+
+// ShardStatus returns the status of every shard, ordered by shard ID.
+func (b *Bot) ShardStatus() []ShardStatus {
+	b.shardsMu.RLock()
+	defer b.shardsMu.RUnlock()
+
+	status := make([]ShardStatus, len(b.shards))
+	for i, s := range b.shards {
+		st := b.shardStatus[i]
+		st.ID = i
+		if st.Connected {
+			st.Latency = s.HeartbeatLatency()
+		}
+		s.State.RLock()
+		st.Guilds = len(s.State.Guilds)
+		s.State.RUnlock()
+		status[i] = st
+	}
+	return status
+}
+
+// openShards creates the shards beyond the first
+// and connects them all to the gateway.
+func (b *Bot) openShards() error {
+	n, concurrency := b.shardCount, 1
+	if n == 0 {
+		gw, err := b.Session.GatewayBot()
+		if err != nil {
+			return errors.Wrap(err, "failed to get recommended shard count")
+		}
+		n = gw.Shards
+		if gw.SessionStartLimit.MaxConcurrency > 1 {
+			concurrency = gw.SessionStartLimit.MaxConcurrency
+		}
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	b.shardsMu.Lock()
+	for len(b.shards) < n {
+		s, err := b.newSession()
+		if err != nil {
+			b.shardsMu.Unlock()
+			return err
+		}
+		s.LogLevel = b.Session.LogLevel
+		for _, h := range b.handlers {
+			h.remove = append(h.remove, s.AddHandler(h.fn))
+		}
+		b.shards = append(b.shards, s)
+	}
+	b.shardStatus = make([]ShardStatus, n)
+	// the server members intent is privileged, so it is
+	// only requested if something needs member events
+	members := b.subscribed(MemberJoinEventName) || b.subscribed(MemberLeaveEventName)
+	for i, s := range b.shards {
+		s.ShardID, s.ShardCount = i, n
+		if members {
+			s.Identify.Intents |= dg.IntentsGuildMembers
+		}
+	}
+	shards := b.shards
+	b.shardsMu.Unlock()
+
+	if n > 1 {
+		b.Logf("starting %d shards", n)
+	}
+	for i, s := range shards {
+		if i > 0 && i%concurrency == 0 {
+			time.Sleep(identifyInterval)
+		}
+		err := s.Open()
+		if err != nil {
+			return errors.Wrapf(err, "shard %d", i)
+		}
+		s := s
+		b.Defer(func() { s.Close() })
+	}
+	return nil
+}
+
+func (b *Bot) setShardStatus(s *dg.Session, connected bool, event string) {
+	b.shardsMu.Lock()
+	if s.ShardID < len(b.shardStatus) {
+		b.shardStatus[s.ShardID].Connected = connected
+		b.shardStatus[s.ShardID].Since = time.Now()
+	}
+	b.shardsMu.Unlock()
+
+	if s.ShardCount > 1 {
+		b.Logf("[shard %d/%d] %s", s.ShardID, s.ShardCount, event)
+	}
+}
+
+func (b *Bot) shardConnect(s *dg.Session, _ *dg.Connect) {
+	b.setShardStatus(s, true, "connected")
+}
+
+func (b *Bot) shardDisconnect(s *dg.Session, _ *dg.Disconnect) {
+	b.setShardStatus(s, false, "disconnected")
+}
+
+func (b *Bot) shardReady(s *dg.Session, r *dg.Ready) {
+	b.setShardStatus(s, true, fmt.Sprintf("ready with %d guilds", len(r.Guilds)))
+}
+
+func (b *Bot) shardResumed(s *dg.Session, _ *dg.Resumed) {
+	b.setShardStatus(s, true, "resumed")
+}
+
+type shardsCommand struct {
+	*Bot
+}
+
+func (c shardsCommand) Name() string {
+	return "shards"
+}
+
+func (c shardsCommand) Comment() string {
+	return "show shard status"
+}
+
+func (c shardsCommand) Usage() []string {
+	return []string{"shards"}
+}
+
+func (c shardsCommand) Description() string {
+	return "Show the connection status, guild count and latency of each shard."
+}
+
+func (c shardsCommand) Owner() {}
+
+func (c shardsCommand) Execute(s Session, m *dg.Message) {
+	var lines []string
+	for _, st := range c.ShardStatus() {
+		if !st.Connected {
+			lines = append(lines, c.T(m.GuildID, "Shard %d: disconnected", st.ID))
+			continue
+		}
+		lines = append(lines, c.T(m.GuildID, "Shard %d: %d guilds, %v latency", st.ID, st.Guilds, st.Latency.Round(time.Millisecond)))
+	}
+
+	_, err := s.ChannelMessageSend(m.ChannelID, "```\n"+strings.Join(lines, "\n")+"\n```")
+	if err != nil {
+		c.Log("[shards]", err)
+	}
+}
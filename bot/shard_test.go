@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+func TestShardFor(t *testing.T) {
+	b := new(Bot)
+	for i := 0; i < 4; i++ {
+		s, err := dg.New("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.shards = append(b.shards, s)
+	}
+
+	tests := []struct {
+		guildID string
+		shard   int
+	}{
+		{"175928847299117063", 0},
+		{"81384788765712384", 2},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := b.ShardFor(tt.guildID); got != b.shards[tt.shard] {
+			t.Errorf("ShardFor(%q) is not shard %d", tt.guildID, tt.shard)
+		}
+	}
+}
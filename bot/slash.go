@@ -0,0 +1,441 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+// slashConfig is read from the "slash" config key:
+//
+//	"slash": {"guilds": ["1234"]}
+//
+// If the key exists, commands are registered as slash commands when the
+// bot connects, in the listed guilds or globally if none are listed.
+type slashConfig struct {
+	Guilds []string
+}
+
+const (
+	maxDescription = 100
+	maxChoices     = 25
+)
+
+var slashName = regexp.MustCompile(`^[-_\p{Ll}\p{N}]{1,32}$`)
+
+var optionTypes = []dg.ApplicationCommandOptionType{
+	StringArgument:     dg.ApplicationCommandOptionString,
+	IntegerArgument:    dg.ApplicationCommandOptionInteger,
+	NumberArgument:     dg.ApplicationCommandOptionNumber,
+	BooleanArgument:    dg.ApplicationCommandOptionBoolean,
+	UserArgument:       dg.ApplicationCommandOptionUser,
+	ChannelArgument:    dg.ApplicationCommandOptionChannel,
+	AttachmentArgument: dg.ApplicationCommandOptionAttachment,
+}
+
+func description(s, fallback string) string {
+	if s == "" {
+		s = fallback
+	}
+	if r := []rune(s); len(r) > maxDescription {
+		s = string(r[:maxDescription-1]) + "…"
+	}
+	return s
+}
+
+// applicationCommands returns the slash commands for the bot's commands.
+// Hidden commands and commands whose names are not valid slash command
+// names are left out. Owner commands are only shown to administrators
+// by default, and are still restricted to the owner when used.
+func (b *Bot) applicationCommands() []*dg.ApplicationCommand {
+	b.commandsMu.RLock()
+	defer b.commandsMu.RUnlock()
+
+	var cmds []*dg.ApplicationCommand
+	for name, cmd := range b.commands {
+		if IsHiddenCommand(cmd) || !slashName.MatchString(name) {
+			continue
+		}
+
+		ac := &dg.ApplicationCommand{
+			Name:        name,
+			Description: description(cmd.Comment(), name),
+		}
+		if IsOwnerCommand(cmd) {
+			var none int64
+			ac.DefaultMemberPermissions = &none
+		}
+		var contexts []dg.InteractionContextType
+		if CommandContexts(cmd)&GuildContext != 0 {
+			contexts = append(contexts, dg.InteractionContextGuild)
+		}
+		if CommandContexts(cmd)&DMContext != 0 {
+			contexts = append(contexts, dg.InteractionContextBotDM)
+		}
+		ac.Contexts = &contexts
+		for _, arg := range CommandArguments(cmd) {
+			ac.Options = append(ac.Options, &dg.ApplicationCommandOption{
+				Type:         optionTypes[arg.Type],
+				Name:         arg.Name,
+				Description:  description(arg.Description, arg.Name),
+				Required:     arg.Required,
+				Autocomplete: arg.Complete != nil && arg.Type == StringArgument,
+			})
+		}
+		// required options must come first
+		sort.SliceStable(ac.Options, func(i, j int) bool {
+			return ac.Options[i].Required && !ac.Options[j].Required
+		})
+		cmds = append(cmds, ac)
+	}
+
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	return cmds
+}
+
+// commandKey returns a string that is equal for
+// commands Discord would treat as unchanged.
+func commandKey(cmd *dg.ApplicationCommand) string {
+	type option struct {
+		Type         dg.ApplicationCommandOptionType
+		Name         string
+		Description  string
+		Required     bool
+		Autocomplete bool
+	}
+	var key struct {
+		Description string
+		Permissions *int64
+		Contexts    *[]dg.InteractionContextType
+		Options     []option
+	}
+	key.Description = cmd.Description
+	key.Permissions = cmd.DefaultMemberPermissions
+	key.Contexts = cmd.Contexts
+	for _, o := range cmd.Options {
+		key.Options = append(key.Options, option{o.Type, o.Name, o.Description, o.Required, o.Autocomplete})
+	}
+	b, _ := json.Marshal(key)
+	return string(b)
+}
+
+// syncCommands makes the slash commands registered in a guild,
+// or globally if guildID is empty, match the bot's commands.
+func (b *Bot) syncCommands(s *dg.Session, appID, guildID string) error {
+	existing, err := s.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return err
+	}
+
+	old := make(map[string]*dg.ApplicationCommand, len(existing))
+	for _, cmd := range existing {
+		old[cmd.Name] = cmd
+	}
+
+	var created, updated, deleted int
+	for _, cmd := range b.applicationCommands() {
+		prev, ok := old[cmd.Name]
+		delete(old, cmd.Name)
+		switch {
+		case !ok:
+			_, err = s.ApplicationCommandCreate(appID, guildID, cmd)
+			created++
+		case commandKey(prev) != commandKey(cmd):
+			_, err = s.ApplicationCommandEdit(appID, guildID, prev.ID, cmd)
+			updated++
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("slash command %q: %v", cmd.Name, err)
+		}
+	}
+	for _, cmd := range old {
+		err = s.ApplicationCommandDelete(appID, guildID, cmd.ID)
+		if err != nil {
+			return fmt.Errorf("slash command %q: %v", cmd.Name, err)
+		}
+		deleted++
+	}
+
+	where := "globally"
+	if guildID != "" {
+		where = "in guild " + guildID
+	}
+	b.Logf("synced slash commands %s: %d created, %d updated, %d deleted", where, created, updated, deleted)
+	return nil
+}
+
+func (b *Bot) ready(s *dg.Session, r *dg.Ready) {
+	// commands are registered for the application, not a shard
+	if b.slash == nil || s.ShardID != 0 {
+		return
+	}
+
+	guilds := b.slash.Guilds
+	if len(guilds) == 0 {
+		guilds = []string{""}
+	}
+	go func() {
+		for _, guildID := range guilds {
+			err := b.syncCommands(s, r.User.ID, guildID)
+			if err != nil {
+				b.Logf("failed to sync slash commands: %v", err)
+			}
+		}
+	}()
+}
+
+func (b *Bot) interactionCreate(s *dg.Session, i *dg.InteractionCreate) {
+	switch i.Type {
+	case dg.InteractionApplicationCommand:
+		b.slashCommand(s, i.Interaction)
+	case dg.InteractionApplicationCommandAutocomplete:
+		b.autocomplete(s, i.Interaction)
+	case dg.InteractionMessageComponent:
+		b.component(s, i.Interaction)
+	}
+}
+
+// handleComponent sets the function handling interactions with message
+// components whose custom IDs start with prefix followed by a colon.
+func (b *Bot) handleComponent(prefix string, fn func(*dg.Session, *dg.Interaction)) {
+	b.componentsMu.Lock()
+	b.components[prefix] = fn
+	b.componentsMu.Unlock()
+}
+
+func (b *Bot) component(s *dg.Session, i *dg.Interaction) {
+	if b.ignoredInteraction(i) {
+		b.respondEphemeral(s, i, b.T(i.GuildID, "You cannot use that here."))
+		return
+	}
+
+	id := i.MessageComponentData().CustomID
+	if n := strings.Index(id, ":"); n >= 0 {
+		id = id[:n]
+	}
+
+	b.componentsMu.RLock()
+	fn := b.components[id]
+	b.componentsMu.RUnlock()
+	if fn != nil {
+		b.safely(fmt.Sprintf("%q component", id), "", func() { fn(s, i) })
+	}
+}
+
+func interactionUser(i *dg.Interaction) *dg.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// ignoredInteraction reports whether the bot ignores
+// the user of an interaction where it was made.
+func (b *Bot) ignoredInteraction(i *dg.Interaction) bool {
+	var roles []string
+	if i.Member != nil {
+		roles = i.Member.Roles
+	}
+	return b.ignored(interactionUser(i), roles, i.ChannelID)
+}
+
+func (b *Bot) slashCommand(s *dg.Session, i *dg.Interaction) {
+	data := i.ApplicationCommandData()
+	user := interactionUser(i)
+	if b.ignoredInteraction(i) {
+		b.respondEphemeral(s, i, b.T(i.GuildID, "You cannot use commands here."))
+		return
+	}
+
+	cmd := b.GetCommand(data.Name)
+	if cmd == nil {
+		b.respondEphemeral(s, i, b.T(i.GuildID, "Command not found."))
+		return
+	}
+	msg := &dg.Message{
+		ID:        i.ID,
+		ChannelID: i.ChannelID,
+		GuildID:   i.GuildID,
+		Author:    user,
+		Member:    i.Member,
+	}
+	var args []string
+	for _, arg := range CommandArguments(cmd) {
+		opt := findOption(data.Options, arg.Name)
+		if opt == nil {
+			continue
+		}
+		if arg.Type == AttachmentArgument {
+			id, _ := opt.Value.(string)
+			if data.Resolved != nil && data.Resolved.Attachments[id] != nil {
+				msg.Attachments = append(msg.Attachments, data.Resolved.Attachments[id])
+			}
+			continue
+		}
+		args = append(args, optionText(opt))
+	}
+	msg.Content = strings.Join(args, " ")
+	content := msg.Content
+
+	if _, text := b.refusal(cmd, data.Name, msg, content); text != "" {
+		b.respondEphemeral(s, i, text)
+		return
+	}
+
+	err := s.InteractionRespond(i, &dg.InteractionResponse{
+		Type: dg.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		b.Logf("failed to respond to interaction: %v", err)
+		return
+	}
+
+	is := &interactionSession{Session: DiscordSession(s), s: s, i: i}
+	b.runCommand(is, cmd, data.Name, b.CommandPlugin(data.Name), msg, content, is.finish)
+}
+
+func (b *Bot) respondEphemeral(s *dg.Session, i *dg.Interaction, content string) {
+	err := s.InteractionRespond(i, &dg.InteractionResponse{
+		Type: dg.InteractionResponseChannelMessageWithSource,
+		Data: &dg.InteractionResponseData{
+			Content: content,
+			Flags:   dg.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		b.Logf("failed to respond to interaction: %v", err)
+	}
+}
+
+func (b *Bot) autocomplete(s *dg.Session, i *dg.Interaction) {
+	data := i.ApplicationCommandData()
+
+	var choices []*dg.ApplicationCommandOptionChoice
+	if cmd := b.GetCommand(data.Name); cmd != nil {
+		for _, opt := range data.Options {
+			if !opt.Focused {
+				continue
+			}
+			for _, arg := range CommandArguments(cmd) {
+				if arg.Name != opt.Name || arg.Complete == nil {
+					continue
+				}
+				for _, v := range arg.Complete(opt.StringValue()) {
+					if len(choices) == maxChoices {
+						break
+					}
+					choices = append(choices, &dg.ApplicationCommandOptionChoice{Name: v, Value: v})
+				}
+			}
+		}
+	}
+
+	err := s.InteractionRespond(i, &dg.InteractionResponse{
+		Type: dg.InteractionApplicationCommandAutocompleteResult,
+		Data: &dg.InteractionResponseData{Choices: choices},
+	})
+	if err != nil {
+		b.Logf("failed to respond to autocomplete: %v", err)
+	}
+}
+
+func findOption(opts []*dg.ApplicationCommandInteractionDataOption, name string) *dg.ApplicationCommandInteractionDataOption {
+	for _, opt := range opts {
+		if opt.Name == name {
+			return opt
+		}
+	}
+	return nil
+}
+
+// optionText formats an option's value as it would be typed in a message.
+func optionText(opt *dg.ApplicationCommandInteractionDataOption) string {
+	switch opt.Type {
+	case dg.ApplicationCommandOptionInteger:
+		return strconv.FormatInt(opt.IntValue(), 10)
+	case dg.ApplicationCommandOptionNumber:
+		return strconv.FormatFloat(opt.FloatValue(), 'g', -1, 64)
+	case dg.ApplicationCommandOptionBoolean:
+		return strconv.FormatBool(opt.BoolValue())
+	case dg.ApplicationCommandOptionUser:
+		return "<@" + fmt.Sprint(opt.Value) + ">"
+	case dg.ApplicationCommandOptionChannel:
+		return "<#" + fmt.Sprint(opt.Value) + ">"
+	default:
+		return fmt.Sprint(opt.Value)
+	}
+}
+
+// interactionSession is the Session passed to commands used as slash
+// commands. The first message sent to the interaction's channel replaces
+// the deferred response and later messages are sent as followups.
+type interactionSession struct {
+	Session
+	s *dg.Session
+	i *dg.Interaction
+
+	mu      sync.Mutex
+	replied bool
+}
+
+func (is *interactionSession) ChannelMessageSend(channelID, content string) (*dg.Message, error) {
+	return is.ChannelMessageSendComplex(channelID, &dg.MessageSend{Content: content})
+}
+
+func (is *interactionSession) ChannelMessageSendEmbed(channelID string, embed *dg.MessageEmbed) (*dg.Message, error) {
+	return is.ChannelMessageSendComplex(channelID, &dg.MessageSend{Embeds: []*dg.MessageEmbed{embed}})
+}
+
+func (is *interactionSession) ChannelMessageSendComplex(channelID string, data *dg.MessageSend) (*dg.Message, error) {
+	if channelID != is.i.ChannelID {
+		return is.Session.ChannelMessageSendComplex(channelID, data)
+	}
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if !is.replied {
+		is.replied = true
+		return is.s.InteractionResponseEdit(is.i, &dg.WebhookEdit{
+			Content:         &data.Content,
+			Embeds:          &data.Embeds,
+			Components:      &data.Components,
+			Files:           data.Files,
+			AllowedMentions: data.AllowedMentions,
+		})
+	}
+	return is.s.FollowupMessageCreate(is.i, true, &dg.WebhookParams{
+		Content:         data.Content,
+		Embeds:          data.Embeds,
+		Components:      data.Components,
+		Files:           data.Files,
+		AllowedMentions: data.AllowedMentions,
+	})
+}
+
+// ChannelMessageDelete ignores attempts to delete the invoking
+// message, since a slash command has none.
+func (is *interactionSession) ChannelMessageDelete(channelID, messageID string) error {
+	if messageID == is.i.ID {
+		return nil
+	}
+	return is.Session.ChannelMessageDelete(channelID, messageID)
+}
+
+// finish removes the deferred response if the command sent nothing.
+func (is *interactionSession) finish() {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	if !is.replied {
+		is.replied = true
+		is.s.InteractionResponseDelete(is.i)
+	}
+}
@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+)
+
+func TestApplicationCommands(t *testing.T) {
+	nop := func(Session, *dg.Message) {}
+	b := &Bot{commands: make(map[string]Command)}
+	b.AddCommand(SimpleCommand("plain", nop, SimpleCommandInfo{Comment: "a command"}))
+	b.AddCommand(ToContextCommand(SimpleCommand("guild", nop, SimpleCommandInfo{}), GuildContext))
+	b.AddCommand(ToOwnerCommand(ToArgumentCommand(SimpleCommand("owner", nop, SimpleCommandInfo{}),
+		Argument{Name: "optional"},
+		Argument{Name: "required", Type: IntegerArgument, Required: true},
+	)))
+	b.AddCommand(ToHiddenCommand(SimpleCommand("hidden", nop, SimpleCommandInfo{})))
+	b.AddCommand(SimpleCommand("\U0001F3B2", nop, SimpleCommandInfo{}))
+
+	cmds := b.applicationCommands()
+	if len(cmds) != 3 {
+		t.Fatalf("got %d commands, want 3", len(cmds))
+	}
+
+	guild, owner, plain := cmds[0], cmds[1], cmds[2]
+	if guild.Contexts == nil || len(*guild.Contexts) != 1 || (*guild.Contexts)[0] != dg.InteractionContextGuild {
+		t.Errorf("got contexts %v, want only guilds", guild.Contexts)
+	}
+	if plain.Contexts == nil || len(*plain.Contexts) != 2 {
+		t.Errorf("got contexts %v, want guilds and DMs", plain.Contexts)
+	}
+	if owner.DefaultMemberPermissions == nil || *owner.DefaultMemberPermissions != 0 {
+		t.Error("owner command is not restricted by default")
+	}
+	if owner.Description != "owner" {
+		t.Errorf("got description %q, want the name", owner.Description)
+	}
+	if len(owner.Options) != 2 || owner.Options[0].Name != "required" || owner.Options[0].Type != dg.ApplicationCommandOptionInteger {
+		t.Errorf("required option is not first: %+v", owner.Options)
+	}
+
+	if plain.DefaultMemberPermissions != nil {
+		t.Error("plain command is restricted")
+	}
+	if len(plain.Options) != 1 || plain.Options[0].Name != "text" || plain.Options[0].Required {
+		t.Errorf("got options %+v, want an optional text option", plain.Options)
+	}
+
+	// Discord omits fields and assigns IDs, which must not count as changes.
+	registered := *plain
+	registered.ID = "1"
+	registered.Options = []*dg.ApplicationCommandOption{{
+		Type:        dg.ApplicationCommandOptionString,
+		Name:        "text",
+		Description: "arguments",
+	}}
+	if commandKey(&registered) != commandKey(plain) {
+		t.Error("unchanged command compares as changed")
+	}
+	registered.Description = "changed"
+	if commandKey(&registered) == commandKey(plain) {
+		t.Error("changed command compares as unchanged")
+	}
+}
+
+// recordingTransport answers every request with an empty
+// JSON object and records the requests' bodies.
+type recordingTransport struct {
+	mu     sync.Mutex
+	bodies []map[string]json.RawMessage
+}
+
+func (rt *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	body := make(map[string]json.RawMessage)
+	if r.Body != nil {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(b, &body)
+	}
+	rt.mu.Lock()
+	rt.bodies = append(rt.bodies, body)
+	rt.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+		Request:    r,
+	}, nil
+}
+
+func TestInteractionSessionSend(t *testing.T) {
+	rt := new(recordingTransport)
+	s, err := dg.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Client = &http.Client{Transport: rt}
+
+	is := &interactionSession{s: s, i: &dg.Interaction{AppID: "app", Token: "token", ChannelID: "c"}}
+	data := &dg.MessageSend{
+		Content:         "page",
+		Components:      []dg.MessageComponent{dg.ActionsRow{Components: []dg.MessageComponent{dg.Button{Label: "▶", CustomID: "next"}}}},
+		AllowedMentions: &dg.MessageAllowedMentions{},
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := is.ChannelMessageSendComplex("c", data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(rt.bodies) != 2 {
+		t.Fatalf("got %d requests, want a response edit and a followup", len(rt.bodies))
+	}
+	for i, body := range rt.bodies {
+		if !strings.Contains(string(body["components"]), `"custom_id":"next"`) {
+			t.Errorf("request %d has components %s", i, body["components"])
+		}
+		if _, ok := body["allowed_mentions"]; !ok {
+			t.Errorf("request %d has no allowed mentions", i)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package bot
+
+import "strings"
+
+// CommandSource provides commands that are not added to the bot,
+// such as ones users define while it runs. Its commands are used when
+// no added command has the name invoked, and are listed in help in a
+// section of their own, titled with the source's name.
+type CommandSource interface {
+	Name() string                         // help section title
+	Command(guildID, name string) Command // nil if there is none
+	Commands(guildID string) []Command    // the commands usable in a guild
+}
+
+type commandSource struct {
+	CommandSource
+	plugin string // plugin that added the source, if any
+}
+
+// AddCommandSource adds a source of commands to the bot.
+// If called while loading a plugin, the source is
+// removed when the plugin is unloaded.
+func (b *Bot) AddCommandSource(src CommandSource) {
+	b.commandsMu.Lock()
+	defer b.commandsMu.Unlock()
+	b.sources = append(b.sources, commandSource{src, b.loading})
+}
+
+// removeSources removes the command sources added by a plugin.
+func (b *Bot) removeSources(plugin string) {
+	b.commandsMu.Lock()
+	defer b.commandsMu.Unlock()
+	// the slice may be in use by sourceCommand
+	var kept []commandSource
+	for _, src := range b.sources {
+		if src.plugin != plugin {
+			kept = append(kept, src)
+		}
+	}
+	b.sources = kept
+}
+
+// sourceCommand returns the command with a name, in lower case, from the
+// first source that has one for the guild with the given ID, and the
+// source's plugin, or nil if none has.
+func (b *Bot) sourceCommand(guildID, name string) (Command, string) {
+	name = strings.ToLower(name)
+
+	b.commandsMu.RLock()
+	sources := b.sources
+	b.commandsMu.RUnlock()
+
+	for _, src := range sources {
+		if cmd := src.Command(guildID, name); cmd != nil {
+			return cmd, src.plugin
+		}
+	}
+	return nil, ""
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/template"
+	"github.com/pkg/errors"
+)
+
+// loadTemplateCfg reads the budget templates are rendered with from the
+// "templates" config key, which holds the fields of a template.Budget.
+// Fields it leaves out or sets to zero keep their defaults.
+func (b *Bot) loadTemplateCfg() error {
+	var budget template.Budget
+	if b.Config.Exists("templates") {
+		err := b.Config.Get("templates", &budget)
+		if err != nil {
+			return err
+		}
+	}
+	if budget.Steps < 0 || budget.Output < 0 {
+		return errors.Errorf("negative template budget %+v", budget)
+	}
+
+	if budget.Steps == 0 {
+		budget.Steps = template.DefaultBudget.Steps
+	}
+	if budget.Output == 0 {
+		budget.Output = template.DefaultBudget.Output
+	}
+	b.templateBudget = budget
+	return nil
+}
+
+// TemplateVars returns the variables templates are rendered with for
+// a message: user, the name shown for its author; mention, which
+// mentions its author; channel and guild, the names of where it was
+// sent, if known; and args, which is set to args.
+func (b *Bot) TemplateVars(s Session, m *dg.Message, args string) map[string]string {
+	vars := map[string]string{
+		"user":    m.Author.Username,
+		"mention": "<@" + m.Author.ID + ">",
+		"channel": "",
+		"guild":   "",
+		"args":    args,
+	}
+	if ch, err := s.Channel(m.ChannelID); err == nil {
+		vars["channel"] = ch.Name
+	}
+	if m.GuildID == "" {
+		return vars
+	}
+	if g, err := s.Guild(m.GuildID); err == nil {
+		vars["guild"] = g.Name
+	}
+	mem := m.Member
+	if mem == nil {
+		mem, _ = s.GuildMember(m.GuildID, m.Author.ID)
+	}
+	if mem != nil && mem.Nick != "" {
+		vars["user"] = mem.Nick
+	}
+	return vars
+}
+
+// RenderTemplate renders text as a template with the given variables.
+// If it cannot be rendered, the error is logged and the text is
+// returned as it is.
+func (b *Bot) RenderTemplate(text string, vars map[string]string) string {
+	out, err := template.Render(text, template.Env{Vars: vars, Budget: b.templateBudget})
+	if err != nil {
+		b.Logf("[templates] %v", err)
+		return text
+	}
+	return out
+}
+
+// Render renders text as a template with the variables for a message,
+// as for TemplateVars and RenderTemplate.
+func (b *Bot) Render(s Session, m *dg.Message, text, args string) string {
+	return b.RenderTemplate(text, b.TemplateVars(s, m, args))
+}
@@ -0,0 +1,32 @@
+package bot_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestTemplateBudget(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{
+		"owner":     "owner",
+		"sigil":     "!",
+		"templates": map[string]interface{}{"Output": 8000},
+	})
+	if got := b.RenderTemplate("hi {user}", map[string]string{"user": "Nick"}); got != "hi Nick" {
+		t.Errorf("got %q with only the output budget set, want %q", got, "hi Nick")
+	}
+	if got := b.RenderTemplate(strings.Repeat("x", 6000)+"{user}", map[string]string{"user": "!"}); !strings.HasSuffix(got, "x!") {
+		t.Errorf("output budget was not raised: got %q", got[len(got)-10:])
+	}
+
+	_, err := bot.NewBot(bottest.NewConfig(t, map[string]interface{}{
+		"owner":     "owner",
+		"sigil":     "!",
+		"templates": map[string]interface{}{"Steps": -1},
+	}))
+	if err == nil || !strings.Contains(err.Error(), "negative template budget") {
+		t.Errorf("got %v, want an error for a negative budget", err)
+	}
+}
@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"strings"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupported is returned by Session methods
+// a transport has no equivalent for.
+var ErrUnsupported = errors.New("not supported by transport")
+
+// Transport is the interface for serving commands over
+// chat services other than Discord. Open connects to the
+// service; from then on the transport passes each message
+// it receives to the bot's Dispatch method.
+type Transport interface {
+	Name() string
+	Open(*Bot) error
+	Close() error
+}
+
+// AddTransport adds a transport to the bot.
+// Transports are opened by Run and closed by Stop.
+func (b *Bot) AddTransport(t Transport) {
+	b.transportsMu.Lock()
+	b.transports = append(b.transports, t)
+	b.transportsMu.Unlock()
+}
+
+func (b *Bot) openTransports() error {
+	b.transportsMu.Lock()
+	defer b.transportsMu.Unlock()
+
+	for _, t := range b.transports {
+		t := t
+		err := t.Open(b)
+		if err != nil {
+			return errors.Wrapf(err, "open transport %q failed", t.Name())
+		}
+		b.Defer(func() {
+			err := t.Close()
+			if err != nil {
+				b.Logf("[%s] %v", t.Name(), err)
+			}
+		})
+	}
+	return nil
+}
+
+// EmbedText renders an embed as lines of plain text
+// for transports that cannot display embeds.
+func EmbedText(e *dg.MessageEmbed) []string {
+	var lines []string
+	add := func(s string) {
+		lines = append(lines, strings.Split(s, "\n")...)
+	}
+
+	if e.Author != nil && e.Author.Name != "" {
+		add(e.Author.Name)
+	}
+	switch {
+	case e.Title != "" && e.URL != "":
+		add(e.Title + " <" + e.URL + ">")
+	case e.Title != "":
+		add(e.Title)
+	case e.URL != "":
+		add("<" + e.URL + ">")
+	}
+	if e.Description != "" {
+		add(e.Description)
+	}
+	for _, f := range e.Fields {
+		if strings.Contains(f.Value, "\n") {
+			add(f.Name)
+			for _, v := range strings.Split(f.Value, "\n") {
+				add("  " + v)
+			}
+		} else {
+			add(strings.TrimSuffix(f.Name, ":") + ": " + f.Value)
+		}
+	}
+	if e.Image != nil && e.Image.URL != "" {
+		add(e.Image.URL)
+	}
+	if e.Footer != nil && e.Footer.Text != "" {
+		add("— " + e.Footer.Text)
+	}
+	return lines
+}
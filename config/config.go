@@ -13,7 +13,7 @@ type Config struct {
 	mu  sync.RWMutex
 	cfg map[string]json.RawMessage
 
-	filename string // immutable
+	filename string // immutable, empty if not read from a file of its own
 }
 
 // New creates a Config and loads the file.
@@ -75,3 +75,49 @@ func (c *Config) Get(key string, value interface{}) error {
 
 	return nil
 }
+
+// Instances returns the configs of the bot instances defined under the
+// "bots" key, an object holding the config of each instance by name.
+// An instance has the keys of its own config and the top-level keys it
+// does not set, so settings can be shared between instances, and its
+// "name" key is its name unless it sets its own. Without a "bots" key,
+// c is the only instance and is returned with an empty name.
+func (c *Config) Instances() (map[string]*Config, error) {
+	if !c.Exists("bots") {
+		return map[string]*Config{"": c}, nil
+	}
+
+	var bots map[string]map[string]json.RawMessage
+	err := c.Get("bots", &bots)
+	if err != nil {
+		return nil, err
+	}
+	if len(bots) == 0 {
+		return nil, errors.New("no bots defined in config")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	instances := make(map[string]*Config, len(bots))
+	for name, values := range bots {
+		if name == "" {
+			return nil, errors.New("bot with an empty name in config")
+		}
+
+		cfg := make(map[string]json.RawMessage, len(c.cfg)+len(values)+1)
+		for k, v := range c.cfg {
+			if k != "bots" {
+				cfg[k] = v
+			}
+		}
+		cfg["name"], _ = json.Marshal(name)
+		for k, v := range values {
+			cfg[k] = v
+		}
+		// an instance is not the whole file, so it must never be
+		// reloaded from or saved to it
+		instances[name] = &Config{cfg: cfg}
+	}
+	return instances, nil
+}
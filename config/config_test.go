@@ -0,0 +1,94 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/njhanley/stoopid/config"
+)
+
+func newConfig(t *testing.T, text string) *config.Config {
+	t.Helper()
+
+	f, err := ioutil.TempFile("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.New(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func get(t *testing.T, c *config.Config, key string) string {
+	t.Helper()
+
+	var s string
+	if err := c.Get(key, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestInstances(t *testing.T) {
+	c := newConfig(t, `{
+		"owner": "owner",
+		"sigil": "!",
+		"bots": {
+			"test": {"token": "a", "sigil": "?"},
+			"prod": {"token": "b", "name": "production"}
+		}
+	}`)
+
+	instances, err := c.Instances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("got %d instances, want 2", len(instances))
+	}
+
+	tests := []struct {
+		instance, key, want string
+	}{
+		{"test", "name", "test"},
+		{"test", "token", "a"},
+		{"test", "sigil", "?"},
+		{"test", "owner", "owner"},
+		{"prod", "name", "production"},
+		{"prod", "token", "b"},
+		{"prod", "sigil", "!"},
+	}
+	for _, tt := range tests {
+		if got := get(t, instances[tt.instance], tt.key); got != tt.want {
+			t.Errorf("%s: got %s %q, want %q", tt.instance, tt.key, got, tt.want)
+		}
+	}
+	if instances["test"].Exists("bots") {
+		t.Error("instance has the bots key")
+	}
+}
+
+func TestInstancesSingle(t *testing.T) {
+	c := newConfig(t, `{"owner": "owner", "sigil": "!"}`)
+
+	instances, err := c.Instances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 1 || instances[""] != c {
+		t.Errorf("got %v, want the config as the only instance", instances)
+	}
+}
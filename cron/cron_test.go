@@ -0,0 +1,64 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2020, time.January, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2020, time.January, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, time.January, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2020, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2020, time.January, 16, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon-fri", time.Date(2020, time.January, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * sat,7", time.Date(2020, time.January, 18, 12, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2020, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 JAN *", time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"5/20 10 * * *", time.Date(2020, time.January, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 0 20 * fri", time.Date(2020, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2020, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2020, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(from); !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@often",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("%q parsed without error", expr)
+		}
+	}
+}
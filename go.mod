@@ -3,7 +3,7 @@ module github.com/njhanley/stoopid
 go 1.13
 
 require (
-	github.com/bwmarrin/discordgo v0.20.2
+	github.com/bwmarrin/discordgo v0.29.0
 	github.com/pkg/errors v0.8.1
-	golang.org/x/sys v0.0.0-20191228213918-04cbcbbfeed8
+	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68
 )
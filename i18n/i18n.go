@@ -0,0 +1,181 @@
+// Package i18n translates messages and formats numbers for locales.
+//
+// Messages are identified by their English text, so a message with no
+// translation is shown in English. Locales are loaded from a directory
+// of JSON files named after the locale they describe, like de.json:
+//
+//	{
+//		"number": {"decimal": ",", "group": "."},
+//		"messages": {
+//			"Command not found.": "Befehl nicht gefunden.",
+//			"For more information, use %shelp <command>": "Mehr Informationen mit %shelp <Befehl>"
+//		}
+//	}
+//
+// Translations of messages with arguments use the same
+// formatting verbs as the message, as in fmt.Sprintf.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Default is the locale of messages without translations.
+const Default = "en"
+
+// NumberFormat describes how numbers are written.
+type NumberFormat struct {
+	Decimal string // decimal separator
+	Group   string // separator between groups of thousands
+}
+
+var defaultNumber = NumberFormat{Decimal: ".", Group: ","}
+
+// Locale holds the translations and number format of a locale.
+type Locale struct {
+	Number   NumberFormat
+	Messages map[string]string
+}
+
+// Catalog holds locales. The zero value holds none and is ready to use.
+type Catalog struct {
+	mu      sync.RWMutex
+	locales map[string]*Locale
+}
+
+// Load creates a catalog from the JSON files in a directory.
+func Load(dir string) (*Catalog, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(Catalog)
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read locale")
+		}
+		l := new(Locale)
+		err = json.Unmarshal(b, l)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal locale %s", file)
+		}
+		c.Add(strings.TrimSuffix(filepath.Base(file), ".json"), l)
+	}
+	return c, nil
+}
+
+// Add adds a locale, replacing any with the same name.
+func (c *Catalog) Add(name string, l *Locale) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.locales == nil {
+		c.locales = make(map[string]*Locale)
+	}
+	c.locales[normalize(name)] = l
+}
+
+// Locales returns the names of the locales in the catalog, sorted.
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.locales))
+	for name := range c.locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether a locale, or the language
+// it is a variant of, is in the catalog.
+func (c *Catalog) Has(name string) bool {
+	return c.lookup(name) != nil
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.Replace(name, "_", "-", -1))
+}
+
+// lookup returns a locale, or else the language it is a variant of,
+// so that "pt-BR" falls back to "pt".
+func (c *Catalog) lookup(name string) *Locale {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	name = normalize(name)
+	if l, ok := c.locales[name]; ok {
+		return l
+	}
+	if n := strings.Index(name, "-"); n >= 0 {
+		return c.locales[name[:n]]
+	}
+	return nil
+}
+
+// Translate returns the translation of msg for a locale, or msg itself if
+// there is none, formatted with args if any are given.
+func (c *Catalog) Translate(locale, msg string, args ...interface{}) string {
+	if l := c.lookup(locale); l != nil {
+		if t, ok := l.Messages[msg]; ok {
+			msg = t
+		}
+	}
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// FormatNumber formats a number for a locale with the given number of
+// decimal places, or as few as needed to represent it exactly if negative.
+func (c *Catalog) FormatNumber(locale string, f float64, decimals int) string {
+	nf := defaultNumber
+	if l := c.lookup(locale); l != nil {
+		if l.Number.Decimal != "" {
+			nf.Decimal = l.Number.Decimal
+		}
+		if l.Number.Group != "" {
+			nf.Group = l.Number.Group
+		}
+	}
+	return nf.Format(f, decimals)
+}
+
+// Format formats a number with the given number of decimal places,
+// or as few as needed to represent it exactly if negative.
+func (nf NumberFormat) Format(f float64, decimals int) string {
+	s := strconv.FormatFloat(f, 'f', decimals, 64)
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	integer, frac := s, ""
+	if n := strings.Index(s, "."); n >= 0 {
+		integer, frac = s[:n], s[n+1:]
+	}
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(nf.Group)
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString(nf.Decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
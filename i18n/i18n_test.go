@@ -0,0 +1,56 @@
+package i18n
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	c := new(Catalog)
+	c.Add("de", &Locale{Messages: map[string]string{
+		"Command not found.": "Befehl nicht gefunden.",
+		"Page %d/%d":         "Seite %d/%d",
+	}})
+
+	tests := []struct {
+		locale, msg string
+		args        []interface{}
+		want        string
+	}{
+		{"de", "Command not found.", nil, "Befehl nicht gefunden."},
+		{"de-AT", "Command not found.", nil, "Befehl nicht gefunden."},
+		{"de", "Page %d/%d", []interface{}{1, 2}, "Seite 1/2"},
+		{"de", "Untranslated.", nil, "Untranslated."},
+		{"fr", "Command not found.", nil, "Command not found."},
+		{"en", "Page %d/%d", []interface{}{1, 2}, "Page 1/2"},
+	}
+	for _, tt := range tests {
+		if got := c.Translate(tt.locale, tt.msg, tt.args...); got != tt.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", tt.locale, tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	c := new(Catalog)
+	c.Add("de", &Locale{Number: NumberFormat{Decimal: ",", Group: "."}})
+
+	tests := []struct {
+		locale   string
+		f        float64
+		decimals int
+		want     string
+	}{
+		{"en", 1234567.891, 2, "1,234,567.89"},
+		{"de", 1234567.891, 2, "1.234.567,89"},
+		{"de", -1234.5, -1, "-1.234,5"},
+		{"en", 999, 0, "999"},
+		{"en", 0.00012, -1, "0.00012"},
+		{"en", math.Round(-100000), 0, "-100,000"},
+	}
+	for _, tt := range tests {
+		if got := c.FormatNumber(tt.locale, tt.f, tt.decimals); got != tt.want {
+			t.Errorf("FormatNumber(%q, %v, %d) = %q, want %q", tt.locale, tt.f, tt.decimals, got, tt.want)
+		}
+	}
+}
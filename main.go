@@ -2,31 +2,56 @@ package main
 
 import (
 	"flag"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
 
 	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/irc"
 	"github.com/njhanley/stoopid/config"
 	"github.com/njhanley/stoopid/plugins/avatar"
+	"github.com/njhanley/stoopid/plugins/crypto"
 	"github.com/njhanley/stoopid/plugins/eightball"
+	"github.com/njhanley/stoopid/plugins/external"
+	"github.com/njhanley/stoopid/plugins/locale"
 	"github.com/njhanley/stoopid/plugins/name"
 	"github.com/njhanley/stoopid/plugins/roll"
 	"github.com/njhanley/stoopid/plugins/say"
 	"github.com/njhanley/stoopid/plugins/status"
+	"github.com/njhanley/stoopid/plugins/tags"
+	"github.com/njhanley/stoopid/plugins/xkcd"
+	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
 )
 
-var plugins = []bot.Plugin{
-	avatar.Plugin(),
-	eightball.Plugin(),
-	name.Plugin(),
-	roll.Plugin(),
-	say.Plugin(),
-	status.Plugin(),
+// bundled returns new instances of the plugins built into the bot,
+// as each bot needs its own.
+func bundled() []bot.Plugin {
+	return []bot.Plugin{
+		avatar.Plugin(),
+		crypto.Plugin(),
+		eightball.Plugin(),
+		locale.Plugin(),
+		name.Plugin(),
+		roll.Plugin(),
+		say.Plugin(),
+		status.Plugin(),
+		tags.Plugin(),
+		xkcd.Plugin(),
+	}
 }
 
-var cfgfile = flag.String("c", "config.json", "config file")
+var (
+	cfgfile = flag.String("c", "config.json", "config file")
+	console = flag.Bool("console", false, "read commands from standard input instead of connecting to Discord")
+	gendocs = flag.Bool("gen-docs", false, "write command reference docs instead of connecting to Discord")
+	docsdir = flag.String("docs-dir", "docs", "directory to write command reference docs to")
+	botname = flag.String("bot", "", "name of the bot to run, or to use with -console or -gen-docs, if the config defines several")
+)
 
 func main() {
 	flag.Parse()
@@ -36,25 +61,216 @@ func main() {
 		log.Fatal(err)
 	}
 
-	bot, err := bot.NewBot(cfg)
+	instances, err := cfg.Instances()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *botname != "" {
+		c, ok := instances[*botname]
+		if !ok {
+			log.Fatalf("no bot named %q in config", *botname)
+		}
+		instances = map[string]*config.Config{*botname: c}
+	}
+	if (*gendocs || *console) && len(instances) > 1 {
+		log.Fatal("the config defines several bots; choose one with -bot")
+	}
+	err = checkIsolated(instances)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	for _, p := range plugins {
-		err = bot.AddPlugin(p)
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bots := make([]*bot.Bot, len(names))
+	for i, name := range names {
+		bots[i], err = newBot(instances[name])
 		if err != nil {
+			if name != "" {
+				err = errors.Wrapf(err, "bot %q", name)
+			}
 			log.Fatal(err)
 		}
 	}
 
-	err = bot.Run()
+	if *gendocs {
+		err = genDocs(bots[0], *docsdir)
+		bots[0].Stop()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *console {
+		err = bots[0].RunConsole(os.Stdin, os.Stdout)
+		bots[0].Stop()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err = run(bots)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer bot.Stop()
+	defer func() {
+		for _, b := range bots {
+			b.Stop()
+		}
+	}()
 
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, unix.SIGINT, unix.SIGTERM)
 	<-sc
 }
+
+// newBot creates a bot and loads the plugins and transports its config
+// asks for. The "plugins" config key lists the bundled plugins to load;
+// without it, all of them are.
+func newBot(cfg *config.Config) (*bot.Bot, error) {
+	b, err := bot.NewBot(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := bundled()
+	if cfg.Exists("plugins") {
+		var want []string
+		err = cfg.Get("plugins", &want)
+		if err != nil {
+			return nil, err
+		}
+		plugins, err = choosePlugins(plugins, want)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ext, err := external.Plugins(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.AddPlugins(append(plugins, ext...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	transports, err := irc.Transports(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transports {
+		b.AddTransport(t)
+	}
+	return b, nil
+}
+
+// choosePlugins returns the plugins with the names wanted.
+func choosePlugins(plugins []bot.Plugin, want []string) ([]bot.Plugin, error) {
+	byName := make(map[string]bot.Plugin, len(plugins))
+	for _, p := range plugins {
+		byName[p.Name()] = p
+	}
+
+	chosen := make([]bot.Plugin, 0, len(want))
+	for _, name := range want {
+		p, ok := byName[name]
+		if !ok {
+			return nil, errors.Errorf("no bundled plugin named %q", name)
+		}
+		chosen = append(chosen, p)
+	}
+	return chosen, nil
+}
+
+// checkIsolated returns an error if bot instances share a token or a
+// store file, which would have them fight over a connection or data.
+func checkIsolated(instances map[string]*config.Config) error {
+	for _, key := range []string{"token", "store"} {
+		seen := make(map[string]string)
+		for name, cfg := range instances {
+			if !cfg.Exists(key) {
+				continue
+			}
+			var value string
+			err := cfg.Get(key, &value)
+			if err != nil {
+				return err
+			}
+			if value == "" {
+				continue
+			}
+			if other, ok := seen[value]; ok {
+				return errors.Errorf("bots %q and %q have the same %s", other, name, key)
+			}
+			seen[value] = name
+		}
+	}
+	return nil
+}
+
+// run starts the bots at the same time, so none waits on the others
+// connecting. If any fails to start, those that started are stopped.
+func run(bots []*bot.Bot) error {
+	errs := make([]error, len(bots))
+	var wg sync.WaitGroup
+	for i, b := range bots {
+		wg.Add(1)
+		go func(i int, b *bot.Bot) {
+			defer wg.Done()
+			errs[i] = b.Run()
+		}(i, b)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		for _, b := range bots {
+			b.Stop()
+		}
+		if name := bots[i].Name(); name != "" {
+			err = errors.Wrapf(err, "bot %q", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// genDocs writes commands.md and commands.json to dir.
+func genDocs(b *bot.Bot, dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"commands.md", b.WriteDocsMarkdown},
+		{"commands.json", b.WriteDocsJSON},
+	}
+	for _, f := range files {
+		file, err := os.Create(filepath.Join(dir, f.name))
+		if err != nil {
+			return err
+		}
+		err = f.write(file)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
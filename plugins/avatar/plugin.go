@@ -10,41 +10,50 @@ import (
 )
 
 func Plugin() bot.Plugin {
-	return plugin
+	return new(plugin)
 }
 
-var plugin = bot.SimplePlugin("avatar", func(b *bot.Bot) error {
-	logf = b.Logf
-	b.AddCommand(bot.ToOwnerCommand(command))
-	return nil
-})
+type plugin struct {
+	logf        func(format string, v ...interface{})
+	acknowledge func(s bot.Session, m *dg.Message, confirmation string) error
+}
 
-var logf func(format string, v ...interface{})
+func (p *plugin) Name() string {
+	return "avatar"
+}
 
-var command = bot.SimpleCommand("avatar", execute, bot.SimpleCommandInfo{
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.acknowledge = b.Acknowledge
+	command := bot.SimpleCommand("avatar", p.execute, commandInfo)
+	b.AddCommand(bot.ToOwnerCommand(bot.ToArgumentCommand(command, bot.Argument{Name: "image", Description: "the new avatar, or none to reset it", Type: bot.AttachmentArgument})))
+	return nil
+}
+
+var commandInfo = bot.SimpleCommandInfo{
 	Comment:     "change avatar",
 	Usage:       []string{"avatar"},
 	Description: "Change the bot's avatar to the attached image or reset it to default if no image is attached with the command.",
-})
+}
 
-func execute(s *dg.Session, m *dg.Message) {
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
 	n := len(m.Attachments)
 	if n > 1 {
-		logf("[avatar] more than one attachment")
+		p.logf("[avatar] more than one attachment")
 		return
 	}
 
 	avatar := "data:;base64,"
 	if n != 0 {
-		r, err := http.Get(m.Attachments[0].URL)
+		r, err := bot.HTTPClient.Get(m.Attachments[0].URL)
 		if err != nil {
-			logf("[avatar] %v", err)
+			p.logf("[avatar] %v", err)
 			return
 		}
 		b, err := ioutil.ReadAll(r.Body)
 		r.Body.Close()
 		if err != nil {
-			logf("[avatar] %v", err)
+			p.logf("[avatar] %v", err)
 			return
 		}
 
@@ -52,19 +61,19 @@ func execute(s *dg.Session, m *dg.Message) {
 		case "image/gif", "image/jpeg", "image/png":
 			avatar = "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(b)
 		default:
-			logf("[avatar] invalid MIME type %q", mime)
+			p.logf("[avatar] invalid MIME type %q", mime)
 			return
 		}
 	}
 
-	_, err := s.UserUpdate("", "", "", avatar, "")
+	err := s.SetAvatar(avatar)
 	if err != nil {
-		logf("[avatar] %v", err)
+		p.logf("[avatar] %v", err)
 		return
 	}
 
-	err = s.ChannelMessageDelete(m.ChannelID, m.ID)
+	err = p.acknowledge(s, m, "Avatar changed.")
 	if err != nil {
-		logf("[avatar] %v", err)
+		p.logf("[avatar] %v", err)
 	}
 }
@@ -0,0 +1,81 @@
+package avatar
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+// smallest valid PNG header; enough for content sniffing
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestExecute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/avatar.png":
+			w.Write(png)
+		default:
+			w.Write([]byte("not an image"))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		guild       string
+		attachments []string
+		avatar      string
+		deleted     []string
+		sent        []string
+	}{
+		{"reset", "g", nil, "data:;base64,", []string{"msg"}, []string{}},
+		{"image", "g", []string{"/avatar.png"}, "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), []string{"msg"}, []string{}},
+		{"not an image", "g", []string{"/avatar.txt"}, "", nil, []string{}},
+		{"too many", "g", []string{"/avatar.png", "/avatar.png"}, "", nil, []string{}},
+		{"dm", "", nil, "data:;base64,", nil, []string{"Avatar changed."}},
+	}
+
+	p := &plugin{
+		logf:        t.Logf,
+		acknowledge: bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!"}).Acknowledge,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := bottest.Message("c", "owner", "")
+			m.GuildID = tt.guild
+			for _, path := range tt.attachments {
+				m.Attachments = append(m.Attachments, &dg.MessageAttachment{URL: srv.URL + path})
+			}
+
+			s := bottest.NewSession()
+			p.execute(s, m)
+
+			if s.Avatar != tt.avatar {
+				t.Errorf("got avatar %q, want %q", s.Avatar, tt.avatar)
+			}
+			if !reflect.DeepEqual(s.Deleted, tt.deleted) {
+				t.Errorf("deleted %q, want %q", s.Deleted, tt.deleted)
+			}
+			if got := s.Contents(); !reflect.DeepEqual(got, tt.sent) {
+				t.Errorf("sent %q, want %q", got, tt.sent)
+			}
+		})
+	}
+}
+
+func TestResetArgument(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!"})
+	if err := b.AddPlugin(Plugin()); err != nil {
+		t.Fatal(err)
+	}
+	args := bot.CommandArguments(b.GetCommand("avatar"))
+	if len(args) != 1 || args[0].Required {
+		t.Errorf("got arguments %+v, want an optional image so the avatar can be reset", args)
+	}
+}
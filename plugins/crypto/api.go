@@ -3,14 +3,15 @@ package crypto
 import (
 	"encoding/json"
 	"io/ioutil"
-	"net/http"
 	"strconv"
+
+	"github.com/njhanley/stoopid/bot"
 )
 
-const endpoint = "https://api.cryptowat.ch/"
+var endpoint = "https://api.cryptowat.ch/"
 
 func get(request string) ([]byte, error) {
-	r, err := http.Get(endpoint + request)
+	r, err := bot.HTTPClient.Get(endpoint + request)
 	if err != nil {
 		return nil, err
 	}
@@ -1,8 +1,6 @@
 package crypto
 
 import (
-	"fmt"
-	"strconv"
 	"strings"
 
 	dg "github.com/bwmarrin/discordgo"
@@ -10,24 +8,32 @@ import (
 )
 
 func Plugin() bot.Plugin {
-	return plugin
+	return new(plugin)
 }
 
-var plugin = bot.SimplePlugin("crypto", func(b *bot.Bot) error {
-	logf = b.Logf
-	b.AddCommand(command)
-	b.AddCommand(bot.ToHiddenCommand(emojiDownCommand))
-	b.AddCommand(bot.ToHiddenCommand(emojiUpCommand))
-	b.AddCommand(bot.ToHiddenCommand(emojiYenCommand))
-	return nil
-})
+type plugin struct {
+	logf         func(format string, v ...interface{})
+	translate    func(guildID, msg string, args ...interface{}) string
+	formatNumber func(guildID string, f float64, decimals int) string
+	newEmbed     func(guildID string) *bot.EmbedBuilder
+}
 
-var logf func(format string, v ...interface{})
+func (p *plugin) Name() string {
+	return "crypto"
+}
 
-var command = bot.SimpleCommand("crypto", execute, commandInfo)
-var emojiDownCommand = bot.SimpleCommand("\U0001F4C9", execute, commandInfo)
-var emojiUpCommand = bot.SimpleCommand("\U0001F4C8", execute, commandInfo)
-var emojiYenCommand = bot.SimpleCommand("\U0001F4B9", execute, commandInfo)
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.translate = b.T
+	p.formatNumber = b.FormatNumber
+	p.newEmbed = b.NewEmbed
+	command := bot.SimpleCommand("crypto", p.execute, commandInfo)
+	b.AddCommand(bot.ToArgumentCommand(command, bot.Argument{Name: "pair", Description: "a currency pair, like btcusd", Required: true}))
+	for _, emoji := range []string{"\U0001F4C9", "\U0001F4C8", "\U0001F4B9"} {
+		b.AddCommand(bot.ToHiddenCommand(bot.ToAliasCommand(bot.SimpleCommand(emoji, p.execute, commandInfo), command.Name())))
+	}
+	return nil
+}
 
 var commandInfo = bot.SimpleCommandInfo{
 	Comment:     "check exchange rates",
@@ -40,64 +46,64 @@ const (
 	decrease = 0xc60606
 )
 
-func ftos(f float64) string {
-	return strconv.FormatFloat(f, 'G', -1, 64)
+// signed formats a number with its sign, even if positive.
+func (p *plugin) signed(guildID string, f float64, decimals int) string {
+	if f < 0 {
+		return p.formatNumber(guildID, f, decimals)
+	}
+	return "+" + p.formatNumber(guildID, f, decimals)
 }
 
-func execute(s *dg.Session, m *dg.Message) {
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
 	if m.Content == "" {
-		logf("[crypto] no argument")
+		p.logf("[crypto] no argument")
 		return
 	}
 
 	pr, err := GetPair(m.Content)
 	if err != nil {
-		logf("[crypto] %v", err)
+		p.logf("[crypto] %v", err)
 		return
 	}
 	if len(pr.Result.Markets) == 0 {
-		_, err := s.ChannelMessageSend(m.ChannelID, "Invalid pair.")
-		logf("[crypto] %v", err)
+		_, err := s.ChannelMessageSend(m.ChannelID, p.translate(m.GuildID, "Invalid pair."))
+		p.logf("[crypto] %v", err)
 		return
 	}
 	market := pr.Result.Markets[0]
 
 	er, err := GetExchange(market.Exchange)
 	if err != nil {
-		logf("[crypto] %v", err)
+		p.logf("[crypto] %v", err)
 		return
 	}
 	exchange := er.Result
 
 	msr, err := GetMarketSummary(market.Exchange, market.Pair)
 	if err != nil {
-		logf("[crypto] %v", err)
+		p.logf("[crypto] %v", err)
 		return
 	}
 	summary := msr.Result
+	g := m.GuildID
 
-	msg := &dg.MessageEmbed{
-		URL:   "https://cryptowat.ch/" + market.Exchange + "/" + market.Pair,
-		Title: strings.Title(exchange.Name) + ": " + strings.ToUpper(market.Pair),
-		Fields: []*dg.MessageEmbedField{
-			{Name: "Latest", Value: ftos(summary.Price.Last), Inline: true},
-			{Name: "High", Value: ftos(summary.Price.High), Inline: true},
-			{Name: "Low", Value: ftos(summary.Price.Low), Inline: true},
-			{Name: "Change (24H)", Value: fmt.Sprintf("%+.3f%% (%+G)", 100*summary.Price.Change.Percentage, summary.Price.Change.Absolute), Inline: true},
-			{Name: "Volume", Value: ftos(summary.Volume), Inline: true},
-		},
-		Footer: &dg.MessageEmbedFooter{
-			Text: "Data provided by https://cryptowat.ch/",
-		},
-	}
-	if msr.Result.Price.Change.Absolute > 0 {
-		msg.Color = increase
-	} else {
-		msg.Color = decrease
+	color := decrease
+	if summary.Price.Change.Absolute > 0 {
+		color = increase
 	}
 
-	_, err = s.ChannelMessageSendEmbed(m.ChannelID, msg)
+	err = p.newEmbed(g).
+		URL("https://cryptowat.ch/"+market.Exchange+"/"+market.Pair).
+		Title(strings.Title(exchange.Name)+": "+strings.ToUpper(market.Pair)).
+		Color(color).
+		Field(p.translate(g, "Latest"), p.formatNumber(g, summary.Price.Last, -1), true).
+		Field(p.translate(g, "High"), p.formatNumber(g, summary.Price.High, -1), true).
+		Field(p.translate(g, "Low"), p.formatNumber(g, summary.Price.Low, -1), true).
+		Field(p.translate(g, "Change (24H)"), p.signed(g, 100*summary.Price.Change.Percentage, 3)+"% ("+p.signed(g, summary.Price.Change.Absolute, -1)+")", true).
+		Field(p.translate(g, "Volume"), p.formatNumber(g, summary.Volume, -1), true).
+		FooterNote(p.translate(g, "Data provided by %s", "https://cryptowat.ch/")).
+		Send(s, m)
 	if err != nil {
-		logf("[crypto] %v", err)
+		p.logf("[crypto] %v", err)
 	}
 }
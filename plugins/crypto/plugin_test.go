@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot/bottest"
+	"github.com/njhanley/stoopid/i18n"
+)
+
+var responses = map[string]string{
+	"/pairs/btcusd":                  `{"result":{"symbol":"btcusd","markets":[{"exchange":"kraken","pair":"btcusd","active":true}]}}`,
+	"/pairs/nopair":                  `{"result":{"symbol":"nopair","markets":[]}}`,
+	"/exchanges/kraken":              `{"result":{"symbol":"kraken","name":"kraken","active":true}}`,
+	"/markets/kraken/btcusd/summary": `{"result":{"price":{"last":100.5,"high":110,"low":90,"change":{"percentage":0.05,"absolute":5}},"volume":1234}}`,
+	"/pairs/ethusd":                  `{"result":{"symbol":"ethusd","markets":[{"exchange":"kraken","pair":"ethusd","active":true}]}}`,
+	"/markets/kraken/ethusd/summary": `{"result":{"price":{"last":10,"high":11,"low":9,"change":{"percentage":-0.1,"absolute":-1}},"volume":5}}`,
+}
+
+func TestExecute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	old := endpoint
+	endpoint = srv.URL + "/"
+	defer func() { endpoint = old }()
+
+	tests := []struct {
+		guild   string
+		content string
+		text    string
+		title   string
+		color   int
+		fields  []string
+	}{
+		{"", "btcusd", "", "Kraken: BTCUSD", increase, []string{"100.5", "110", "90", "+5.000% (+5)", "1,234"}},
+		{"", "ethusd", "", "Kraken: ETHUSD", decrease, []string{"10", "11", "9", "-10.000% (-1)", "5"}},
+		{"de", "btcusd", "", "Kraken: BTCUSD", increase, []string{"100,5", "110", "90", "+5,000% (+5)", "1.234"}},
+		{"de", "nopair", "Ungültiges Paar.", "", 0, nil},
+		{"", "nopair", "Invalid pair.", "", 0, nil},
+		{"", "", "", "", 0, nil},
+	}
+
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!"})
+	b.Catalog.Add("de", &i18n.Locale{
+		Number:   i18n.NumberFormat{Decimal: ",", Group: "."},
+		Messages: map[string]string{"Invalid pair.": "Ungültiges Paar."},
+	})
+	if err := b.SetLocale("de", "de"); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &plugin{
+		logf:         t.Logf,
+		translate:    b.T,
+		formatNumber: b.FormatNumber,
+		newEmbed:     b.NewEmbed,
+	}
+	for _, tt := range tests {
+		t.Run(tt.guild+" "+tt.content, func(t *testing.T) {
+			s := bottest.NewSession()
+			m := bottest.Message("c", "u", tt.content)
+			m.GuildID = tt.guild
+			p.execute(s, m)
+
+			if tt.text != "" {
+				if got := s.Contents(); !reflect.DeepEqual(got, []string{tt.text}) {
+					t.Errorf("got %q, want %q", got, tt.text)
+				}
+				return
+			}
+
+			embeds := s.Embeds()
+			if tt.title == "" {
+				if len(s.Sent) != 0 {
+					t.Errorf("sent %d messages, want none", len(s.Sent))
+				}
+				return
+			}
+			if len(embeds) != 1 {
+				t.Fatalf("got %d embeds, want 1", len(embeds))
+			}
+			e := embeds[0]
+			if e.Title != tt.title {
+				t.Errorf("got title %q, want %q", e.Title, tt.title)
+			}
+			if e.Color != tt.color {
+				t.Errorf("got color %#x, want %#x", e.Color, tt.color)
+			}
+			values := make([]string, len(e.Fields))
+			for i, f := range e.Fields {
+				values[i] = f.Value
+			}
+			if !reflect.DeepEqual(values, tt.fields) {
+				t.Errorf("got fields %q, want %q", values, tt.fields)
+			}
+		})
+	}
+}
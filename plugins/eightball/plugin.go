@@ -11,25 +11,37 @@ import (
 )
 
 func Plugin() bot.Plugin {
-	return plugin
+	return new(plugin)
 }
 
-var plugin = bot.SimplePlugin("8ball", func(b *bot.Bot) error {
-	logf = b.Logf
+// plugin answers with the responses configured for its bot.
+type plugin struct {
+	logf      func(format string, v ...interface{})
+	translate func(guildID, msg string, args ...interface{}) string
+	render    func(s bot.Session, m *dg.Message, text, args string) string
+
+	answers *responses
+	insults *responses
+}
+
+func (p *plugin) Name() string {
+	return "8ball"
+}
+
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.translate = b.T
+	p.render = b.Render
 	rand.Seed(time.Now().UnixNano())
-	err := configure(b.Config)
+	err := p.configure(b.Config)
 	if err != nil {
 		return err
 	}
-	b.AddCommand(command)
-	b.AddCommand(bot.ToHiddenCommand(emojiCommand))
+	command := bot.SimpleCommand("8ball", p.execute, commandInfo)
+	b.AddCommand(bot.ToArgumentCommand(command, bot.Argument{Name: "question", Description: "a yes-no question"}))
+	b.AddCommand(bot.ToHiddenCommand(bot.ToAliasCommand(bot.SimpleCommand("\U0001F3B1", p.execute, commandInfo), command.Name())))
 	return nil
-})
-
-var logf func(format string, v ...interface{})
-
-var command = bot.SimpleCommand("8ball", execute, commandInfo)
-var emojiCommand = bot.SimpleCommand("\U0001F3B1", execute, commandInfo)
+}
 
 var commandInfo = bot.SimpleCommandInfo{
 	Comment:     "ask a yes-no question",
@@ -48,6 +60,9 @@ type responses struct {
 }
 
 func newResponses(resps []response) *responses {
+	// the defaults are shared by every bot the plugin is loaded into
+	resps = append([]response(nil), resps...)
+
 	var sum float64
 	for _, r := range resps {
 		sum += r.Weight
@@ -69,8 +84,7 @@ func (rs *responses) choose() response {
 }
 
 var (
-	answers, insults *responses
-	defaultAnswers   = []response{
+	defaultAnswers = []response{
 		{[]string{"It is certain."}, 1},
 		{[]string{"It is decidedly so."}, 1},
 		{[]string{"Without a doubt."}, 1},
@@ -99,7 +113,7 @@ var (
 	}
 )
 
-func configure(c *config.Config) error {
+func (p *plugin) configure(c *config.Config) error {
 	var x struct {
 		Answers []response
 		Insults []response
@@ -114,29 +128,29 @@ func configure(c *config.Config) error {
 	if len(x.Answers) == 0 {
 		x.Answers = defaultAnswers
 	}
-	answers = newResponses(x.Answers)
+	p.answers = newResponses(x.Answers)
 
 	if len(x.Insults) == 0 {
 		x.Insults = defaultInsults
 	}
-	insults = newResponses(x.Insults)
+	p.insults = newResponses(x.Insults)
 
 	return nil
 }
 
 var wrongQuestion = regexp.MustCompile("^(?i:how|what|when|where|which|who|why)")
 
-func execute(s *dg.Session, m *dg.Message) {
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
 	var resp response
 	if wrongQuestion.MatchString(m.Content) {
-		resp = insults.choose()
+		resp = p.insults.choose()
 	} else {
-		resp = answers.choose()
+		resp = p.answers.choose()
 	}
 	for _, t := range resp.Text {
-		_, err := s.ChannelMessageSend(m.ChannelID, t)
+		_, err := s.ChannelMessageSend(m.ChannelID, p.render(s, m, p.translate(m.GuildID, t), m.Content))
 		if err != nil {
-			logf("[8ball] %v", err)
+			p.logf("[8ball] %v", err)
 			return
 		}
 	}
@@ -0,0 +1,51 @@
+package eightball
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"", []string{"Yes.", "Probably, useru."}},
+		{"will it rain?", []string{"Yes.", "Probably, useru."}},
+		{"Why is the sky blue?", []string{"How should I know?"}},
+		{"who am I", []string{"How should I know?"}},
+		{"whoa", []string{"How should I know?"}},
+	}
+
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!"})
+	p := &plugin{
+		logf:      t.Logf,
+		translate: b.T,
+		render:    b.Render,
+		answers:   newResponses([]response{{[]string{"Yes.", "Probably, {user}."}, 1}}),
+		insults:   newResponses([]response{{[]string{"How should I know?"}, 1}}),
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			s := bottest.NewSession()
+			p.execute(s, bottest.Message("c", "u", tt.content))
+			if got := s.Contents(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponsesWeights(t *testing.T) {
+	rs := newResponses([]response{
+		{[]string{"never"}, 0},
+		{[]string{"always"}, 3},
+	})
+	for i := 0; i < 100; i++ {
+		if got := rs.choose().Text[0]; got != "always" {
+			t.Fatalf("chose %q with zero weight", got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Package external runs plugins as separate processes.
+
+Each configured executable is started by the bot and spoken to over its
+standard input and output using JSON-RPC 2.0. Every message is a single
+JSON object on its own line. Anything the process writes to standard
+error is copied to the bot's log.
+
+# Configuration
+
+External plugins are listed under the "external" key:
+
+	"external": [
+		{
+			"name": "weather",
+			"path": "/usr/local/bin/weather-plugin",
+			"args": ["-units", "metric"],
+			"timeout": "10s"
+		}
+	]
+
+Name and path are required. Timeout bounds every call made to the
+process and defaults to 10 seconds.
+
+# Bot to plugin
+
+"initialize" is the first request sent after the process starts, and is
+sent again each time the process is restarted.
+
+	--> {"jsonrpc":"2.0","id":1,"method":"initialize","params":{"name":"weather","sigil":"!"}}
+	<-- {"jsonrpc":"2.0","id":1,"result":{"commands":[{"name":"weather","comment":"check the weather","usage":["weather <city>"],"description":"Check the weather in a city."}],"events":["message"]}}
+
+Each command may also set "owner" or "hidden" to true, which correspond
+to bot.OwnerCommand and bot.HiddenCommand. Events lists the events the
+plugin wants to receive; "message" is the only event currently defined.
+
+"execute" is sent when a user invokes one of the plugin's commands. The
+message is a Discord message object with the sigil and command name
+removed from its content. The result lists the replies to send to the
+channel the command was used in; each reply has a "content" string, an
+"embed" object, or both.
+
+	--> {"jsonrpc":"2.0","id":2,"method":"execute","params":{"command":"weather","message":{"id":"...","channel_id":"...","content":"Boston","author":{...}}}}
+	<-- {"jsonrpc":"2.0","id":2,"result":{"replies":[{"content":"Boston: 12°C, cloudy"}]}}
+
+"event" is a notification, so it carries no id and expects no response.
+
+	--> {"jsonrpc":"2.0","method":"event","params":{"type":"message","message":{...}}}
+
+# Plugin to bot
+
+The process may send requests of its own at any time.
+
+"send" posts a message to a channel and returns the new message's ID.
+
+	<-- {"jsonrpc":"2.0","id":"a","method":"send","params":{"channel_id":"...","content":"hello"}}
+	--> {"jsonrpc":"2.0","id":"a","result":{"id":"..."}}
+
+"log" writes a line to the bot's log.
+
+	<-- {"jsonrpc":"2.0","method":"log","params":{"message":"cache refreshed"}}
+
+# Lifecycle
+
+If the process exits while the bot is running it is restarted after a
+delay that doubles with each consecutive failure, up to one minute.
+Commands announced by the restarted process replace the old ones. When
+the bot stops, the process's standard input is closed and it is killed
+if it has not exited within five seconds.
+*/
+package external
@@ -0,0 +1,405 @@
+package external
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	minBackoff     = time.Second
+	maxBackoff     = time.Minute
+	closeGrace     = 5 * time.Second
+)
+
+var errStopped = errors.New("plugin unloaded")
+
+// Config describes an external plugin.
+type Config struct {
+	Name    string
+	Path    string
+	Args    []string
+	Timeout string
+}
+
+// Plugins creates a plugin for each entry under the "external" config key.
+func Plugins(c *config.Config) ([]bot.Plugin, error) {
+	if !c.Exists("external") {
+		return nil, nil
+	}
+
+	var cfgs []Config
+	err := c.Get("external", &cfgs)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := make([]bot.Plugin, len(cfgs))
+	for i, cfg := range cfgs {
+		plugins[i], err = New(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plugins, nil
+}
+
+// New creates a plugin that runs an external process.
+// The process is not started until the plugin is loaded.
+func New(cfg Config) (bot.Plugin, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("external plugin has no name")
+	}
+	if cfg.Path == "" {
+		return nil, errors.Errorf("external plugin %q has no path", cfg.Name)
+	}
+
+	timeout := defaultTimeout
+	if cfg.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, errors.Wrapf(err, "external plugin %q", cfg.Name)
+		}
+	}
+
+	return &plugin{
+		name:    cfg.Name,
+		path:    cfg.Path,
+		args:    cfg.Args,
+		timeout: timeout,
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+type plugin struct {
+	bot *bot.Bot
+
+	mu       sync.RWMutex
+	conn     *conn
+	events   map[string]bool
+	commands []string // names of the commands the process announced
+
+	stopOnce sync.Once
+	stop     chan struct{}
+
+	// immutable
+	name    string
+	path    string
+	args    []string
+	timeout time.Duration
+}
+
+func (p *plugin) Name() string {
+	return p.name
+}
+
+func (p *plugin) Load(b *bot.Bot) error {
+	p.bot = b
+
+	err := p.start()
+	if err != nil {
+		return err
+	}
+
+	b.OnMessage(p.message)
+	b.Defer(p.close)
+
+	go p.supervise()
+	return nil
+}
+
+func (p *plugin) logf(format string, v ...interface{}) {
+	p.bot.Logf("[%s] "+format, append([]interface{}{p.name}, v...)...)
+}
+
+type initializeParams struct {
+	Name  string `json:"name"`
+	Sigil string `json:"sigil"`
+}
+
+type manifest struct {
+	Commands []commandInfo `json:"commands"`
+	Events   []string      `json:"events"`
+}
+
+type commandInfo struct {
+	Name        string   `json:"name"`
+	Comment     string   `json:"comment"`
+	Usage       []string `json:"usage"`
+	Description string   `json:"description"`
+	Owner       bool     `json:"owner"`
+	Hidden      bool     `json:"hidden"`
+}
+
+// start runs the process, performs the handshake,
+// and registers the commands it announces.
+func (p *plugin) start() error {
+	c, err := dial(p.path, p.args, p.handle, p.logf)
+	if err != nil {
+		return err
+	}
+
+	var m manifest
+	err = c.call("initialize", initializeParams{p.name, p.bot.Sigil()}, &m, p.timeout)
+	if err != nil {
+		c.close(closeGrace)
+		return errors.Wrap(err, "initialize failed")
+	}
+
+	events := make(map[string]bool, len(m.Events))
+	for _, e := range m.Events {
+		events[e] = true
+	}
+
+	// the commands are replaced before the process is used, so
+	// commands a restarted process no longer announces are gone
+	// by the time it serves any; they are added for the plugin
+	// by name, since a restarted process is started outside of Load
+	var cmds []bot.Command
+	var names []string
+	for _, info := range m.Commands {
+		if info.Name == "" {
+			p.logf("ignoring command with no name")
+			continue
+		}
+		var cmd bot.Command = &command{p, info}
+		if info.Owner {
+			cmd = bot.ToOwnerCommand(cmd)
+		}
+		if info.Hidden {
+			cmd = bot.ToHiddenCommand(cmd)
+		}
+		cmds = append(cmds, cmd)
+		names = append(names, info.Name)
+	}
+
+	p.mu.Lock()
+	select {
+	case <-p.stop:
+		// unloaded while starting
+		p.mu.Unlock()
+		c.close(closeGrace)
+		return errStopped
+	default:
+	}
+	for _, cmd := range cmds {
+		p.bot.AddPluginCommand(p.name, cmd)
+	}
+	for _, name := range p.commands {
+		if !contains(names, name) {
+			p.bot.RemoveCommand(name)
+		}
+	}
+	p.conn = c
+	p.events = events
+	p.commands = names
+	p.mu.Unlock()
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// supervise restarts the process whenever it exits until the plugin is closed.
+func (p *plugin) supervise() {
+	backoff := minBackoff
+	for {
+		p.mu.RLock()
+		done := p.conn.done
+		p.mu.RUnlock()
+
+		select {
+		case <-done:
+		case <-p.stop:
+			return
+		}
+
+		for {
+			p.logf("process exited, restarting in %v", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-p.stop:
+				return
+			}
+
+			err := p.start()
+			if err == nil {
+				break
+			}
+			p.logf("%v", err)
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		backoff = minBackoff
+	}
+}
+
+func (p *plugin) close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	p.mu.RLock()
+	c := p.conn
+	p.mu.RUnlock()
+	c.close(closeGrace)
+}
+
+// Unload stops the process for good and removes its commands,
+// so a plugin disabled after panicking does not come back
+// when the process would have been restarted.
+func (p *plugin) Unload(b *bot.Bot) {
+	p.close()
+
+	p.mu.Lock()
+	for _, name := range p.commands {
+		b.RemoveCommand(name)
+	}
+	p.commands = nil
+	p.mu.Unlock()
+}
+
+func (p *plugin) current() *conn {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.conn
+}
+
+func (p *plugin) wants(event string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.events[event]
+}
+
+type eventParams struct {
+	Type    string      `json:"type"`
+	Message *dg.Message `json:"message,omitempty"`
+}
+
+func (p *plugin) message(e *bot.MessageEvent) {
+	if !p.wants("message") {
+		return
+	}
+	err := p.current().notify("event", eventParams{Type: "message", Message: e.Message})
+	if err != nil {
+		p.logf("%v", err)
+	}
+}
+
+type sendParams struct {
+	ChannelID string           `json:"channel_id"`
+	Content   string           `json:"content"`
+	Embed     *dg.MessageEmbed `json:"embed"`
+}
+
+type sendResult struct {
+	ID string `json:"id"`
+}
+
+type logParams struct {
+	Message string `json:"message"`
+}
+
+// handle serves requests sent by the process.
+func (p *plugin) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "send":
+		var x sendParams
+		err := json.Unmarshal(params, &x)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := send(bot.DiscordSession(p.bot.Session), x.ChannelID, reply{x.Content, x.Embed})
+		if err != nil {
+			return nil, err
+		}
+		return sendResult{msg.ID}, nil
+	case "log":
+		var x logParams
+		err := json.Unmarshal(params, &x)
+		if err != nil {
+			return nil, err
+		}
+		p.logf("%s", x.Message)
+		return nil, nil
+	default:
+		return nil, &rpcError{codeMethodNotFound, "method not found: " + method}
+	}
+}
+
+type reply struct {
+	Content string           `json:"content"`
+	Embed   *dg.MessageEmbed `json:"embed"`
+}
+
+func send(s bot.Session, channelID string, r reply) (*dg.Message, error) {
+	switch {
+	case r.Embed != nil:
+		return s.ChannelMessageSendComplex(channelID, &dg.MessageSend{Content: r.Content, Embeds: []*dg.MessageEmbed{r.Embed}})
+	case r.Content != "":
+		return s.ChannelMessageSend(channelID, r.Content)
+	default:
+		return nil, errors.New("empty reply")
+	}
+}
+
+type executeParams struct {
+	Command string      `json:"command"`
+	Message *dg.Message `json:"message"`
+}
+
+type executeResult struct {
+	Replies []reply `json:"replies"`
+}
+
+// command forwards invocations to the process.
+type command struct {
+	p    *plugin
+	info commandInfo
+}
+
+func (c *command) Name() string {
+	return c.info.Name
+}
+
+func (c *command) Comment() string {
+	return c.info.Comment
+}
+
+func (c *command) Usage() []string {
+	return append([]string(nil), c.info.Usage...)
+}
+
+func (c *command) Description() string {
+	return c.info.Description
+}
+
+func (c *command) Execute(s bot.Session, m *dg.Message) {
+	var x executeResult
+	err := c.p.current().call("execute", executeParams{c.info.Name, m}, &x, c.p.timeout)
+	if err != nil {
+		c.p.logf("%s: %v", c.info.Name, err)
+		return
+	}
+	for _, r := range x.Replies {
+		_, err = send(s, m.ChannelID, r)
+		if err != nil {
+			c.p.logf("%s: %v", c.info.Name, err)
+			return
+		}
+	}
+}
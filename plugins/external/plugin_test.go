@@ -0,0 +1,213 @@
+package external
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+// TestHelperProcess is not a real test; it is the plugin
+// process started by the other tests.
+func TestHelperProcess(t *testing.T) {
+	args := flag.Args()
+	if len(args) == 0 || args[0] != "helper" {
+		return
+	}
+
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		var m struct {
+			ID     json.RawMessage
+			Method string
+			Params struct {
+				Command string
+				Message struct{ Content string }
+			}
+		}
+		json.Unmarshal(sc.Bytes(), &m)
+
+		var result interface{}
+		switch m.Method {
+		case "initialize":
+			m := manifest{Commands: []commandInfo{
+				{Name: "echo", Comment: "echo a message"},
+				{Name: "crash"},
+				{Name: "flood"},
+				{Name: "sleep"},
+				{Name: "secret", Owner: true, Hidden: true},
+			}}
+			// with a marker file, only the first process announces "gone"
+			if len(args) < 2 || first(args[1]) {
+				m.Commands = append(m.Commands, commandInfo{Name: "gone"})
+			}
+			result = m
+		case "execute":
+			switch m.Params.Command {
+			case "crash":
+				os.Exit(1)
+			case "flood":
+				// too long a line for the bot to read, without exiting
+				fmt.Println(strings.Repeat("x", 2<<20))
+				continue
+			case "sleep":
+				time.Sleep(time.Second)
+			}
+			result = executeResult{Replies: []reply{{Content: m.Params.Message.Content}}}
+		}
+		b, _ := json.Marshal(result)
+		fmt.Printf(`{"jsonrpc":"2.0","id":%s,"result":%s}`+"\n", m.ID, b)
+	}
+	os.Exit(0)
+}
+
+// first reports whether the marker file does not yet exist,
+// creating it.
+func first(marker string) bool {
+	f, err := os.OpenFile(marker, os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+func loadHelper(t *testing.T, args ...string) *bot.Bot {
+	p, err := New(Config{
+		Name:    "helper",
+		Path:    os.Args[0],
+		Args:    append([]string{"-test.run=TestHelperProcess", "--", "helper"}, args...),
+		Timeout: "200ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	err = b.AddPlugin(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCommands(t *testing.T) {
+	b := loadHelper(t)
+	defer b.Stop()
+
+	tests := []struct {
+		command string
+		content string
+		want    []string
+	}{
+		{"echo", "hello", []string{"hello"}},
+		{"secret", "psst", []string{"psst"}},
+		{"sleep", "zzz", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			cmd := b.GetCommand(tt.command)
+			if cmd == nil {
+				t.Fatalf("command %q not registered", tt.command)
+			}
+			s := bottest.NewSession()
+			cmd.Execute(s, bottest.Message("c", "u", tt.content))
+			if got := s.Contents(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	secret := b.GetCommand("secret")
+	if !bot.IsOwnerCommand(secret) || !bot.IsHiddenCommand(secret) {
+		t.Error("secret is not an owner-only hidden command")
+	}
+}
+
+func TestRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := loadHelper(t, filepath.Join(dir, "started"))
+	defer b.Stop()
+	if b.GetCommand("gone") == nil {
+		t.Fatal("command gone not registered")
+	}
+
+	s := bottest.NewSession()
+	b.GetCommand("crash").Execute(s, bottest.Message("c", "u", ""))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		b.GetCommand("echo").Execute(s, bottest.Message("c", "u", "back"))
+		if len(s.Sent) > 0 {
+			if b.GetCommand("gone") != nil {
+				t.Error("command dropped by the restarted process is still registered")
+			}
+			if got := b.CommandPlugin("echo"); got != "helper" {
+				t.Errorf("echo belongs to plugin %q after restarting, want helper", got)
+			}
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("plugin did not restart")
+}
+
+func TestRestartAfterReadError(t *testing.T) {
+	b := loadHelper(t)
+	defer b.Stop()
+
+	s := bottest.NewSession()
+	b.GetCommand("flood").Execute(s, bottest.Message("c", "u", ""))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		b.GetCommand("echo").Execute(s, bottest.Message("c", "u", "back"))
+		if len(s.Sent) > 0 {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("plugin did not restart")
+}
+
+func TestUnload(t *testing.T) {
+	b := loadHelper(t)
+	defer b.Stop()
+	p := b.GetPlugin("helper").(*plugin)
+	c := p.current()
+
+	err := b.UnloadPlugin("helper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.done:
+	default:
+		t.Error("process still running after unloading")
+	}
+
+	// long enough for a restart, were one to happen
+	time.Sleep(2 * minBackoff)
+	if b.GetCommand("echo") != nil {
+		t.Error("command still registered after unloading")
+	}
+	if p.current() != c {
+		t.Error("process restarted after unloading")
+	}
+}
@@ -0,0 +1,276 @@
+package external
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const version = "2.0"
+
+// message is any JSON-RPC object: a request, a notification, or a response.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return "rpc error " + strconv.Itoa(e.Code) + ": " + e.Message
+}
+
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+)
+
+var errExited = errors.New("process exited")
+
+// handlerFunc serves requests and notifications sent by the process.
+// The result is ignored for notifications.
+type handlerFunc func(method string, params json.RawMessage) (interface{}, error)
+
+// conn is a running plugin process.
+type conn struct {
+	cmd     *exec.Cmd
+	handler handlerFunc
+	logf    func(format string, v ...interface{})
+
+	writeMu sync.Mutex
+	stdin   io.WriteCloser
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+
+	done       chan struct{}
+	stderrDone chan struct{} // closed once standard error is read to the end
+}
+
+func dial(path string, args []string, handler handlerFunc, logf func(string, ...interface{})) (*conn, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to start %q", path)
+	}
+
+	c := &conn{
+		cmd:     cmd,
+		handler: handler,
+		logf:    logf,
+		stdin:   stdin,
+		pending: make(map[int64]chan *message),
+		done:    make(chan struct{}),
+
+		stderrDone: make(chan struct{}),
+	}
+
+	go c.logStderr(stderr)
+	go c.read(stdout)
+
+	return c, nil
+}
+
+func (c *conn) logStderr(r io.Reader) {
+	defer close(c.stderrDone)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		c.logf("%s", sc.Text())
+	}
+}
+
+func (c *conn) read(r io.Reader) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var m message
+		err := json.Unmarshal(sc.Bytes(), &m)
+		if err != nil {
+			c.logf("invalid message: %v", err)
+			c.write(&message{ID: json.RawMessage("null"), Error: &rpcError{codeParseError, err.Error()}})
+			continue
+		}
+
+		if m.Method != "" {
+			go c.serve(&m)
+			continue
+		}
+
+		id, err := strconv.ParseInt(string(m.ID), 10, 64)
+		if err != nil {
+			c.logf("response with unknown id %s", m.ID)
+			continue
+		}
+		c.mu.Lock()
+		ch := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- &m
+		}
+	}
+	if err := sc.Err(); err != nil {
+		// the process may still be running, so it is killed
+		// for it to be restarted
+		c.logf("read failed: %v", err)
+		c.cmd.Process.Kill()
+	}
+
+	// Wait closes the pipes, so the last of standard error
+	// must be read first
+	<-c.stderrDone
+	c.cmd.Wait()
+
+	c.mu.Lock()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	close(c.done)
+}
+
+func (c *conn) serve(m *message) {
+	result, err := c.handler(m.Method, m.Params)
+	if len(m.ID) == 0 {
+		if err != nil {
+			c.logf("%s: %v", m.Method, err)
+		}
+		return
+	}
+
+	resp := &message{ID: m.ID}
+	if err != nil {
+		rerr, ok := err.(*rpcError)
+		if !ok {
+			rerr = &rpcError{codeInternalError, err.Error()}
+		}
+		resp.Error = rerr
+	} else {
+		resp.Result, err = json.Marshal(result)
+		if err != nil {
+			resp.Error = &rpcError{codeInternalError, err.Error()}
+		}
+	}
+	err = c.write(resp)
+	if err != nil {
+		c.logf("%s: %v", m.Method, err)
+	}
+}
+
+func (c *conn) write(m *message) error {
+	m.JSONRPC = version
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.stdin.Write(b)
+	return err
+}
+
+// call sends a request and decodes its result into result,
+// failing if no response arrives within timeout.
+func (c *conn) call(method string, params, result interface{}, timeout time.Duration) error {
+	select {
+	case <-c.done:
+		return errExited
+	default:
+	}
+
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan *message, 1)
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	err = c.write(&message{ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method, Params: p})
+	if err != nil {
+		c.forget(id)
+		return err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case m, ok := <-ch:
+		if !ok {
+			return errExited
+		}
+		if m.Error != nil {
+			return m.Error
+		}
+		if result == nil || len(m.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(m.Result, result)
+	case <-timer.C:
+		c.forget(id)
+		return errors.Errorf("%s timed out after %v", method, timeout)
+	}
+}
+
+func (c *conn) forget(id int64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// notify sends a notification, which has no response.
+func (c *conn) notify(method string, params interface{}) error {
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{Method: method, Params: p})
+}
+
+// close asks the process to exit by closing its standard input
+// and kills it if it is still running after the grace period.
+func (c *conn) close(grace time.Duration) {
+	c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(grace):
+		c.cmd.Process.Kill()
+		<-c.done
+	}
+}
@@ -0,0 +1,97 @@
+package locale
+
+import (
+	"strings"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/i18n"
+)
+
+func Plugin() bot.Plugin {
+	return new(plugin)
+}
+
+type plugin struct {
+	logf      func(format string, v ...interface{})
+	translate func(guildID, msg string, args ...interface{}) string
+	getLocale func(guildID string) string
+	setLocale func(guildID, locale string) error
+	locales   func() []string
+}
+
+func (p *plugin) Name() string {
+	return "locale"
+}
+
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.translate = b.T
+	p.getLocale = b.Locale
+	p.setLocale = b.SetLocale
+	p.locales = b.Catalog.Locales
+	b.AddCommand(p.command())
+	return nil
+}
+
+// command returns the locale command, which can only be used in guilds.
+func (p *plugin) command() bot.Command {
+	command := bot.SimpleCommand("locale", p.execute, commandInfo)
+	return bot.ToOwnerCommand(bot.ToContextCommand(bot.ToArgumentCommand(command, bot.Argument{
+		Name:        "locale",
+		Description: "the new locale, or reset",
+		Complete:    p.complete,
+	}), bot.GuildContext))
+}
+
+var commandInfo = bot.SimpleCommandInfo{
+	Comment:     "change the guild's locale",
+	Usage:       []string{"locale", "locale <locale>", "locale reset"},
+	Description: "Show the guild's locale and the locales available, change the guild's locale, or reset it to the default.",
+}
+
+// available returns the default locale and the locales loaded.
+func (p *plugin) available() []string {
+	names := []string{i18n.Default}
+	for _, name := range p.locales() {
+		if name != i18n.Default {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (p *plugin) complete(partial string) []string {
+	var names []string
+	for _, name := range append(p.available(), "reset") {
+		if strings.HasPrefix(name, partial) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
+	var text string
+	switch {
+	case m.Content == "":
+		text = p.translate(m.GuildID, "The locale is %s. Available locales: %s", p.getLocale(m.GuildID), strings.Join(p.available(), ", "))
+	default:
+		locale := m.Content
+		if locale == "reset" {
+			locale = ""
+		}
+		err := p.setLocale(m.GuildID, locale)
+		if err != nil {
+			p.logf("[locale] %v", err)
+			text = p.translate(m.GuildID, "Unknown locale. Available locales: %s", strings.Join(p.available(), ", "))
+			break
+		}
+		text = p.translate(m.GuildID, "The locale is now %s.", p.getLocale(m.GuildID))
+	}
+
+	_, err := s.ChannelMessageSend(m.ChannelID, text)
+	if err != nil {
+		p.logf("[locale] %v", err)
+	}
+}
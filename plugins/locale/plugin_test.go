@@ -0,0 +1,73 @@
+package locale
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+	"github.com/njhanley/stoopid/i18n"
+)
+
+func TestExecute(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	b.Catalog.Add("de", &i18n.Locale{Messages: map[string]string{
+		"The locale is now %s.": "Die Sprache ist jetzt %s.",
+	}})
+
+	p := &plugin{
+		logf:      t.Logf,
+		translate: b.T,
+		getLocale: b.Locale,
+		setLocale: b.SetLocale,
+		locales:   b.Catalog.Locales,
+	}
+
+	tests := []struct {
+		guild   string
+		content string
+		want    string
+		locale  string
+	}{
+		{"g", "", "The locale is en. Available locales: en, de", "en"},
+		{"g", "fr", "Unknown locale. Available locales: en, de", "en"},
+		{"g", "de", "Die Sprache ist jetzt de.", "de"},
+		{"g", "reset", "The locale is now en.", "en"},
+	}
+	for _, tt := range tests {
+		s := bottest.NewSession()
+		m := bottest.Message("c", "owner", tt.content)
+		m.GuildID = tt.guild
+		p.execute(s, m)
+
+		if got := s.Contents(); !reflect.DeepEqual(got, []string{tt.want}) {
+			t.Errorf("%q: got %q, want %q", tt.content, got, tt.want)
+		}
+		if got := b.Locale(tt.guild); got != tt.locale {
+			t.Errorf("%q: got locale %q, want %q", tt.content, got, tt.locale)
+		}
+	}
+}
+
+func TestGuildOnly(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	p := &plugin{
+		logf:      t.Logf,
+		translate: b.T,
+		getLocale: b.Locale,
+		setLocale: b.SetLocale,
+		locales:   b.Catalog.Locales,
+	}
+	b.AddCommand(p.command())
+
+	s := bottest.NewSession()
+	if b.Dispatch(s, "self", bottest.Message("c", "owner", "!locale reset")) {
+		t.Error("locale ran in a DM")
+	}
+	if got := s.Contents(); len(got) != 1 {
+		t.Errorf("got %q, want the command refused", got)
+	}
+	if got := bot.CommandContexts(b.GetCommand("locale")); got != bot.GuildContext {
+		t.Errorf("got contexts %v, want guild only", got)
+	}
+}
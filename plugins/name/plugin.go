@@ -6,36 +6,47 @@ import (
 )
 
 func Plugin() bot.Plugin {
-	return plugin
+	return new(plugin)
 }
 
-var plugin = bot.SimplePlugin("name", func(b *bot.Bot) error {
-	logf = b.Logf
-	b.AddCommand(bot.ToOwnerCommand(command))
-	return nil
-})
+type plugin struct {
+	logf func(format string, v ...interface{})
+}
 
-var logf func(format string, v ...interface{})
+func (p *plugin) Name() string {
+	return "name"
+}
 
-var command = bot.SimpleCommand("name", execute, bot.SimpleCommandInfo{
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	command := bot.SimpleCommand("name", p.execute, commandInfo)
+	b.AddCommand(bot.ToOwnerCommand(bot.ToContextCommand(bot.ToArgumentCommand(command, bot.Argument{Name: "nickname", Description: "the new nickname"}), bot.GuildContext)))
+	return nil
+}
+
+var commandInfo = bot.SimpleCommandInfo{
 	Comment:     "change bot nickname",
 	Usage:       []string{"name [<nickname>]"},
 	Description: "Change or reset the bot's nickname in the guild.",
-})
+}
 
-func execute(s *dg.Session, m *dg.Message) {
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
 	err := s.ChannelMessageDelete(m.ChannelID, m.ID)
 	if err != nil {
-		logf("[name] %v", err)
+		p.logf("[name] %v", err)
 		return
 	}
-	ch, err := s.State.Channel(m.ChannelID)
+	ch, err := s.Channel(m.ChannelID)
 	if err != nil {
-		logf("[name] %v", err)
+		p.logf("[name] %v", err)
+		return
+	}
+	if ch.GuildID == "" {
+		p.logf("[name] channel %s is not in a guild", ch.ID)
 		return
 	}
 	err = s.GuildMemberNickname(ch.GuildID, "@me", m.Content)
 	if err != nil {
-		logf("[name] %v", err)
+		p.logf("[name] %v", err)
 	}
 }
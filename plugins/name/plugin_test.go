@@ -0,0 +1,43 @@
+package name
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		channel   string
+		content   string
+		err       error
+		deleted   []string
+		nicknames map[string]string
+	}{
+		{"set", "c", "bob", nil, []string{"msg"}, map[string]string{"g": "bob"}},
+		{"reset", "c", "", nil, []string{"msg"}, map[string]string{"g": ""}},
+		{"unknown channel", "x", "bob", nil, []string{"msg"}, nil},
+		{"dm", "dm", "bob", nil, []string{"msg"}, nil},
+		{"error", "c", "bob", errors.New("offline"), nil, nil},
+	}
+
+	p := &plugin{logf: t.Logf}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bottest.NewSession(&dg.Channel{ID: "c", GuildID: "g"}, &dg.Channel{ID: "dm", Type: dg.ChannelTypeDM})
+			s.Err = tt.err
+			p.execute(s, bottest.Message(tt.channel, "owner", tt.content))
+
+			if !reflect.DeepEqual(s.Deleted, tt.deleted) {
+				t.Errorf("deleted %q, want %q", s.Deleted, tt.deleted)
+			}
+			if !reflect.DeepEqual(s.Nicknames, tt.nicknames) {
+				t.Errorf("got nicknames %q, want %q", s.Nicknames, tt.nicknames)
+			}
+		})
+	}
+}
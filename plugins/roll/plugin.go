@@ -14,49 +14,83 @@ import (
 )
 
 func Plugin() bot.Plugin {
-	return plugin
+	return new(plugin)
 }
 
-var plugin = bot.SimplePlugin("roll", func(b *bot.Bot) error {
-	logf = b.Logf
+// plugin rolls dice within the limits configured for its bot.
+type plugin struct {
+	logf  func(format string, v ...interface{})
+	reply func(s bot.Session, m *dg.Message, text string) error
+
+	cfg limits
+}
+
+func (p *plugin) Name() string {
+	return "roll"
+}
+
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.reply = b.ReplyText
 	rand.Seed(time.Now().UnixNano())
-	err := configure(b.Config)
+	err := p.configure(b.Config)
 	if err != nil {
 		return err
 	}
-	b.AddCommand(command)
-	b.AddCommand(bot.ToHiddenCommand(emojiCommand))
+	info := p.commandInfo()
+	command := bot.SimpleCommand("roll", p.execute, info)
+	b.AddCommand(bot.ToArgumentCommand(command, arguments...))
+	b.AddCommand(bot.ToHiddenCommand(bot.ToAliasCommand(bot.SimpleCommand("\U0001F3B2", p.execute, info), command.Name())))
 	return nil
-})
+}
+
+var arguments = []bot.Argument{
+	{Name: "dice", Description: "the dice to roll, like 2d6+1", Required: true, Complete: completeDice},
+	{Name: "text", Description: "text to include with the roll"},
+}
 
-var logf func(format string, v ...interface{})
+var commonDice = []string{"d20", "d6", "2d6", "d4", "d8", "d10", "d12", "d100", "4d6"}
 
-var command = bot.SimpleCommand("roll", execute, commandInfo)
-var emojiCommand = bot.SimpleCommand("\U0001F3B2", execute, commandInfo)
+// completeDice suggests common dice starting with partial.
+func completeDice(partial string) []string {
+	var dice []string
+	for _, d := range commonDice {
+		if strings.HasPrefix(d, partial) {
+			dice = append(dice, d)
+		}
+	}
+	return dice
+}
 
-var commandInfo = bot.SimpleCommandInfo{
-	Comment:     "roll dice",
-	Usage:       []string{"roll [<number of dice>]d<number of sides>[+|-<modifier>] [<text>]"},
-	Description: fmt.Sprintf("Roll %d to %d dice each with %d to %d sides with an optional modifier between %d and %d. If <number of dice> is missing, it will default to the minimum. Additional text may be included after the command.", cfg.Dice.Min, cfg.Dice.Max, cfg.Sides.Min, cfg.Sides.Max, cfg.Modifier.Min, cfg.Modifier.Max),
+func (p *plugin) commandInfo() bot.SimpleCommandInfo {
+	return bot.SimpleCommandInfo{
+		Comment:     "roll dice",
+		Usage:       []string{"roll [<number of dice>]d<number of sides>[+|-<modifier>] [<text>]"},
+		Description: fmt.Sprintf("Roll %d to %d dice each with %d to %d sides with an optional modifier between %d and %d. If <number of dice> is missing, it will default to the minimum. Additional text may be included after the command.", p.cfg.Dice.Min, p.cfg.Dice.Max, p.cfg.Sides.Min, p.cfg.Sides.Max, p.cfg.Modifier.Min, p.cfg.Modifier.Max),
+	}
 }
 
 type minmax struct {
 	Min, Max int
 }
 
-var cfg = struct {
+// limits are read from the "roll" config key.
+type limits struct {
 	Dice     minmax
 	Sides    minmax
 	Modifier minmax
-}{
+}
+
+var defaultLimits = limits{
 	Dice:     minmax{1, 100},
 	Sides:    minmax{2, 1000},
 	Modifier: minmax{-1000000, 1000000},
 }
 
-func configure(c *config.Config) error {
+func (p *plugin) configure(c *config.Config) error {
+	p.cfg = defaultLimits
 	if c.Exists("roll") {
-		err := c.Get("roll", &cfg)
+		err := c.Get("roll", &p.cfg)
 		if err != nil {
 			return err
 		}
@@ -66,11 +100,11 @@ func configure(c *config.Config) error {
 
 var rollRegexp = regexp.MustCompile("^([1-9][0-9]*)?d([1-9][0-9]*)([+-][1-9][0-9]*)?(?: .*)?$")
 
-func execute(s *dg.Session, m *dg.Message) {
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
 	// match roll pattern
 	loc := rollRegexp.FindStringSubmatchIndex(m.Content)
 	if loc == nil {
-		logf("[roll] invalid argument %q", m.Content)
+		p.logf("[roll] invalid argument %q", m.Content)
 		return
 	}
 
@@ -82,38 +116,38 @@ func execute(s *dg.Session, m *dg.Message) {
 		tmp = m.Content[loc[2]:loc[3]]
 		dice, err = strconv.Atoi(tmp)
 		if err != nil {
-			logf("[roll] %v", err)
+			p.logf("[roll] %v", err)
 			return
 		}
 	} else {
-		dice = cfg.Dice.Min
+		dice = p.cfg.Dice.Min
 	}
 	tmp = m.Content[loc[4]:loc[5]]
 	sides, err = strconv.Atoi(tmp)
 	if err != nil {
-		logf("[roll] %v", err)
+		p.logf("[roll] %v", err)
 		return
 	}
 	if loc[6] >= 0 {
 		tmp = m.Content[loc[6]:loc[7]]
 		modifier, err = strconv.Atoi(tmp)
 		if err != nil {
-			logf("[roll] %v", err)
+			p.logf("[roll] %v", err)
 			return
 		}
 	}
 
 	// check limits
-	if !(cfg.Dice.Min <= dice && dice <= cfg.Dice.Max) {
-		logf("[roll] dice out of bounds (%d, min = %d, max = %d)", dice, cfg.Dice.Min, cfg.Dice.Max)
+	if !(p.cfg.Dice.Min <= dice && dice <= p.cfg.Dice.Max) {
+		p.logf("[roll] dice out of bounds (%d, min = %d, max = %d)", dice, p.cfg.Dice.Min, p.cfg.Dice.Max)
 		return
 	}
-	if !(cfg.Sides.Min <= sides && sides <= cfg.Sides.Max) {
-		logf("[roll] sides out of bounds (%d, min = %d, max = %d)", sides, cfg.Sides.Min, cfg.Sides.Max)
+	if !(p.cfg.Sides.Min <= sides && sides <= p.cfg.Sides.Max) {
+		p.logf("[roll] sides out of bounds (%d, min = %d, max = %d)", sides, p.cfg.Sides.Min, p.cfg.Sides.Max)
 		return
 	}
-	if !(cfg.Modifier.Min <= modifier && modifier <= cfg.Modifier.Max) {
-		logf("[roll] modifier out of bounds (%d, min = %d, max = %d)", modifier, cfg.Modifier.Min, cfg.Modifier.Max)
+	if !(p.cfg.Modifier.Min <= modifier && modifier <= p.cfg.Modifier.Max) {
+		p.logf("[roll] modifier out of bounds (%d, min = %d, max = %d)", modifier, p.cfg.Modifier.Min, p.cfg.Modifier.Max)
 		return
 	}
 
@@ -138,8 +172,8 @@ func execute(s *dg.Session, m *dg.Message) {
 		text += " = " + strconv.Itoa(total)
 	}
 
-	_, err = s.ChannelMessageSend(m.ChannelID, text)
+	err = p.reply(s, m, text)
 	if err != nil {
-		logf("[roll] %v", err)
+		p.logf("[roll] %v", err)
 	}
 }
@@ -0,0 +1,80 @@
+package roll
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string // pattern for the reply, empty for no reply
+	}{
+		{"d6", `^[1-6]$`},
+		{"d6 for initiative", `^[1-6]$`},
+		{"2d6", `^[1-6] \+ [1-6] = ([2-9]|1[0-2])$`},
+		{"d6+3", `^[1-6] \+ 3 = [4-9]$`},
+		{"d6-3", `^[1-6] - 3 = (-?[0-3])$`},
+		{"", ""},
+		{"d", ""},
+		{"0d6", ""},
+		{"d1", ""},
+		{"101d6", ""},
+		{"d1001", ""},
+		{"d6+1000001", ""},
+		{"d6for", ""},
+	}
+
+	p := &plugin{
+		logf:  t.Logf,
+		reply: bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!"}).ReplyText,
+		cfg:   defaultLimits,
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			s := bottest.NewSession()
+			p.execute(s, bottest.Message("c", "u", tt.content))
+
+			got := s.Contents()
+			if tt.want == "" {
+				if len(got) != 0 {
+					t.Errorf("got replies %q, want none", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("got replies %q, want one", got)
+			}
+			if !regexp.MustCompile(tt.want).MatchString(got[0]) {
+				t.Errorf("got reply %q, want match for %s", got[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestLongRoll(t *testing.T) {
+	p := &plugin{
+		logf:  t.Logf,
+		reply: bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!"}).ReplyText,
+		cfg:   defaultLimits,
+	}
+	p.cfg.Dice.Max = 1000
+
+	s := bottest.NewSession()
+	p.execute(s, bottest.Message("c", "u", "500d1000"))
+	got := s.Contents()
+	if len(got) < 2 {
+		t.Fatalf("got %d replies, want the roll split between several", len(got))
+	}
+	for _, text := range got {
+		if len(text) > 2000 {
+			t.Errorf("got a reply %d long", len(text))
+		}
+	}
+	if !strings.Contains(got[len(got)-1], " = ") {
+		t.Errorf("last reply %q has no total", got[len(got)-1])
+	}
+}
@@ -6,37 +6,50 @@ import (
 )
 
 func Plugin() bot.Plugin {
-	return plugin
+	return new(plugin)
 }
 
-var plugin = bot.SimplePlugin("say", func(b *bot.Bot) error {
-	logf = b.Logf
-	b.AddCommand(bot.ToOwnerCommand(command))
-	return nil
-})
+type plugin struct {
+	logf        func(format string, v ...interface{})
+	acknowledge func(s bot.Session, m *dg.Message, confirmation string) error
+	render      func(s bot.Session, m *dg.Message, text, args string) string
+	reply       func(s bot.Session, m *dg.Message, text string) error
+}
 
-var logf func(format string, v ...interface{})
+func (p *plugin) Name() string {
+	return "say"
+}
 
-var command = bot.SimpleCommand("say", execute, bot.SimpleCommandInfo{
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.acknowledge = b.Acknowledge
+	p.render = b.Render
+	p.reply = b.ReplyText
+	command := bot.SimpleCommand("say", p.execute, commandInfo)
+	b.AddCommand(bot.ToOwnerCommand(bot.ToArgumentCommand(command, bot.Argument{Name: "message", Description: "the message to say", Required: true})))
+	return nil
+}
+
+var commandInfo = bot.SimpleCommandInfo{
 	Comment:     "say a message",
 	Usage:       []string{"say <message>"},
-	Description: "Make the bot say the message.",
-})
+	Description: "Make the bot say the message. The message may hold placeholders like {user}, {channel}, {choose:a|b} and {roll:2d6}.",
+}
 
-func execute(s *dg.Session, m *dg.Message) {
-	err := s.ChannelMessageDelete(m.ChannelID, m.ID)
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
+	err := p.acknowledge(s, m, "")
 	if err != nil {
-		logf("[say] %v", err)
+		p.logf("[say] %v", err)
 		return
 	}
 
 	if m.Content == "" {
-		logf("[say] no argument")
+		p.logf("[say] no argument")
 		return
 	}
 
-	_, err = s.ChannelMessageSend(m.ChannelID, m.Content)
+	err = p.reply(s, m, p.render(s, m, m.Content, m.Content))
 	if err != nil {
-		logf("[say] %v", err)
+		p.logf("[say] %v", err)
 	}
 }
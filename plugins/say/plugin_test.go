@@ -0,0 +1,47 @@
+package say
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name    string
+		guild   string
+		content string
+		err     error
+		deleted []string
+		sent    []string
+	}{
+		{"message", "g", "hello there", nil, []string{"msg"}, []string{"hello there"}},
+		{"no message", "g", "", nil, []string{"msg"}, []string{}},
+		{"error", "g", "hello", errors.New("offline"), nil, []string{}},
+		{"dm", "", "hello there", nil, nil, []string{"hello there"}},
+		{"template", "g", "hi {user} in #{channel}", nil, []string{"msg"}, []string{"hi Nick in #general"}},
+	}
+
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!"})
+	p := &plugin{logf: t.Logf, acknowledge: b.Acknowledge, render: b.Render, reply: b.ReplyText}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bottest.NewSession(&dg.Channel{ID: "c", GuildID: "g", Name: "general"})
+			s.AddMember(&dg.Member{GuildID: "g", Nick: "Nick", User: &dg.User{ID: "owner"}})
+			s.Err = tt.err
+			m := bottest.Message("c", "owner", tt.content)
+			m.GuildID = tt.guild
+			p.execute(s, m)
+
+			if !reflect.DeepEqual(s.Deleted, tt.deleted) {
+				t.Errorf("deleted %q, want %q", s.Deleted, tt.deleted)
+			}
+			if got := s.Contents(); !reflect.DeepEqual(got, tt.sent) {
+				t.Errorf("sent %q, want %q", got, tt.sent)
+			}
+		})
+	}
+}
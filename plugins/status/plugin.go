@@ -6,31 +6,43 @@ import (
 )
 
 func Plugin() bot.Plugin {
-	return plugin
+	return new(plugin)
 }
 
-var plugin = bot.SimplePlugin("status", func(b *bot.Bot) error {
-	logf = b.Logf
-	b.AddCommand(bot.ToOwnerCommand(command))
-	return nil
-})
+type plugin struct {
+	logf        func(format string, v ...interface{})
+	acknowledge func(s bot.Session, m *dg.Message, confirmation string) error
+	render      func(s bot.Session, m *dg.Message, text, args string) string
+}
+
+func (p *plugin) Name() string {
+	return "status"
+}
 
-var logf func(format string, v ...interface{})
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.acknowledge = b.Acknowledge
+	p.render = b.Render
+	command := bot.SimpleCommand("status", p.execute, commandInfo)
+	b.AddCommand(bot.ToOwnerCommand(bot.ToArgumentCommand(command, bot.Argument{Name: "game", Description: "the game to show"})))
+	return nil
+}
 
-var command = bot.SimpleCommand("status", execute, bot.SimpleCommandInfo{
+var commandInfo = bot.SimpleCommandInfo{
 	Comment:     "change bot status",
 	Usage:       []string{"status [<game>]"},
-	Description: "Change the bot's status.",
-})
+	Description: "Change the bot's status. The status may hold placeholders like {choose:a|b} and {roll:d20}, filled in when it is changed.",
+}
 
-func execute(s *dg.Session, m *dg.Message) {
-	err := s.ChannelMessageDelete(m.ChannelID, m.ID)
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
+	err := s.SetStatus(p.render(s, m, m.Content, m.Content))
 	if err != nil {
-		logf("[status] %v", err)
+		p.logf("[status] %v", err)
 		return
 	}
-	err = s.UpdateStatus(0, m.Content)
+
+	err = p.acknowledge(s, m, "Status changed.")
 	if err != nil {
-		logf("[status] %v", err)
+		p.logf("[status] %v", err)
 	}
 }
@@ -0,0 +1,50 @@
+package status
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name    string
+		guild   string
+		content string
+		err     error
+		deleted []string
+		status  string
+		sent    []string
+	}{
+		{"set", "g", "a game", nil, []string{"msg"}, "a game", []string{}},
+		{"clear", "g", "", nil, []string{"msg"}, "", []string{}},
+		{"error", "g", "a game", errors.New("offline"), nil, "old", []string{}},
+		{"dm", "", "a game", nil, nil, "a game", []string{"Status changed."}},
+		{"template", "g", "{choose:rolling {roll:1d1}}", nil, []string{"msg"}, "rolling 1", []string{}},
+	}
+
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!"})
+	p := &plugin{logf: t.Logf, acknowledge: b.Acknowledge, render: b.Render}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := bottest.NewSession()
+			s.Status = "old"
+			s.Err = tt.err
+			m := bottest.Message("c", "owner", tt.content)
+			m.GuildID = tt.guild
+			p.execute(s, m)
+
+			if !reflect.DeepEqual(s.Deleted, tt.deleted) {
+				t.Errorf("deleted %q, want %q", s.Deleted, tt.deleted)
+			}
+			if s.Status != tt.status {
+				t.Errorf("got status %q, want %q", s.Status, tt.status)
+			}
+			if got := s.Contents(); !reflect.DeepEqual(got, tt.sent) {
+				t.Errorf("sent %q, want %q", got, tt.sent)
+			}
+		})
+	}
+}
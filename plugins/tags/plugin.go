@@ -0,0 +1,383 @@
+package tags
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/store"
+)
+
+func Plugin() bot.Plugin {
+	return new(plugin)
+}
+
+// plugin serves the tags kept in its bot's store.
+type plugin struct {
+	logf      func(format string, v ...interface{})
+	translate func(guildID, msg string, args ...interface{}) string
+	sigil     func() string
+	isOwner   func(userID string) bool
+	render    func(s bot.Session, m *dg.Message, text, args string) string
+	reply     func(s bot.Session, m *dg.Message, data *dg.MessageSend) error
+	isCommand func(name string) bool
+	db        *store.Store
+
+	// mu guards changes to tags
+	mu sync.Mutex
+}
+
+func (p *plugin) Name() string {
+	return "tags"
+}
+
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.translate = b.T
+	p.sigil = b.Sigil
+	p.isOwner = b.IsOwner
+	p.render = b.Render
+	p.reply = b.Reply
+	p.isCommand = func(name string) bool { return b.GetCommand(name) != nil }
+	p.db = b.Store
+	b.AddCommand(bot.ToContextCommand(bot.SimpleCommand("tag", p.execute, commandInfo), bot.GuildContext))
+	b.AddCommandSource(source{p})
+	return nil
+}
+
+const (
+	maxName = 32
+	maxText = 2000
+)
+
+var subcommands = []string{"add", "edit", "delete", "list", "info"}
+
+var commandInfo = bot.SimpleCommandInfo{
+	Comment: "manage the guild's tags",
+	Usage: []string{
+		"tag <name>",
+		"tag add <name> <text>",
+		"tag edit <name> <text>",
+		"tag delete <name>",
+		"tag list",
+		"tag info <name>",
+	},
+	Description: "Tags are responses anyone can add to a guild. Once added, a tag is sent by using its name as a command. " +
+		"Tags may hold placeholders like {user}, {args}, {choose:a|b} and {roll:2d6}. " +
+		"Only the person who added a tag, or someone who can manage messages, can edit or delete it.",
+}
+
+// tag is a response added to a guild.
+type tag struct {
+	Name      string    `json:"name"`
+	Text      string    `json:"text"`
+	OwnerID   string    `json:"owner_id"`
+	OwnerName string    `json:"owner_name"`
+	Created   time.Time `json:"created"`
+	Edited    time.Time `json:"edited"`
+	Uses      int       `json:"uses"`
+}
+
+func key(guildID, name string) string {
+	return "tags/" + guildID + "/" + name
+}
+
+// get returns a guild's tag with a name, or nil if there is none.
+func (p *plugin) get(guildID, name string) *tag {
+	if guildID == "" || name == "" {
+		return nil
+	}
+	t := new(tag)
+	if p.db.Get(key(guildID, name), t) != nil {
+		return nil
+	}
+	return t
+}
+
+// list returns a guild's tags, sorted by name.
+func (p *plugin) list(guildID string) []*tag {
+	var tags []*tag
+	for _, k := range p.db.Keys(key(guildID, "")) {
+		t := new(tag)
+		err := p.db.Get(k, t)
+		if err != nil {
+			p.logf("[tags] %v", err)
+			continue
+		}
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags
+}
+
+// cut splits s into its first word and the rest, trimmed.
+func cut(s string) (word, rest string) {
+	s = strings.TrimSpace(s)
+	n := strings.IndexFunc(s, unicode.IsSpace)
+	if n < 0 {
+		return s, ""
+	}
+	return s[:n], strings.TrimSpace(s[n:])
+}
+
+// validName returns why a name cannot be used for a new tag,
+// or the empty string if it can.
+func (p *plugin) validName(guildID, name string) string {
+	if name == "" || len([]rune(name)) > maxName {
+		return p.translate(guildID, "Tag names must be 1 to %d characters long.", maxName)
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return p.translate(guildID, "Tag names may only have letters, digits, dashes and underscores.")
+		}
+	}
+	for _, sub := range subcommands {
+		if name == sub {
+			return p.translate(guildID, "%q cannot be used as a tag name.", name)
+		}
+	}
+	if p.isCommand(name) {
+		return p.translate(guildID, "There is already a command named %q.", name)
+	}
+	if p.get(guildID, name) != nil {
+		return p.translate(guildID, "There is already a tag named %q.", name)
+	}
+	return ""
+}
+
+// canChange reports whether a user may edit or delete a tag.
+func (p *plugin) canChange(s bot.Session, m *dg.Message, t *tag) bool {
+	if m.Author.ID == t.OwnerID || p.isOwner(m.Author.ID) {
+		return true
+	}
+	perms, err := s.UserChannelPermissions(m.Author.ID, m.ChannelID)
+	if err != nil {
+		p.logf("[tags] %v", err)
+		return false
+	}
+	return perms&dg.PermissionManageMessages != 0
+}
+
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
+	sub, rest := cut(m.Content)
+	name, text := cut(rest)
+	name = strings.ToLower(name)
+
+	var reply string
+	switch sub {
+	case "":
+		reply = p.translate(m.GuildID, "To use a tag, use %s<name>. For a list of tags, use %stag list", p.sigil(), p.sigil())
+	case "add":
+		reply = p.add(m, name, text)
+	case "edit":
+		reply = p.edit(s, m, name, text)
+	case "delete":
+		reply = p.remove(s, m, name)
+	case "list":
+		reply = p.listText(m.GuildID)
+	case "info":
+		reply = p.info(m.GuildID, name)
+	default:
+		if rest == "" {
+			if t := p.get(m.GuildID, strings.ToLower(sub)); t != nil {
+				p.send(s, m, t, "")
+				return
+			}
+		}
+		reply = p.translate(m.GuildID, "No tag named %q.", sub)
+	}
+
+	// replies may echo what the user typed, which must not mention anyone
+	err := p.reply(s, m, &dg.MessageSend{
+		Content:         reply,
+		AllowedMentions: &dg.MessageAllowedMentions{},
+	})
+	if err != nil {
+		p.logf("[tags] %v", err)
+	}
+}
+
+func (p *plugin) add(m *dg.Message, name, text string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if why := p.validName(m.GuildID, name); why != "" {
+		return why
+	}
+	if why := p.validText(m.GuildID, text); why != "" {
+		return why
+	}
+
+	err := p.db.Set(key(m.GuildID, name), &tag{
+		Name:      name,
+		Text:      text,
+		OwnerID:   m.Author.ID,
+		OwnerName: m.Author.Username,
+		Created:   time.Now(),
+	})
+	if err != nil {
+		p.logf("[tags] %v", err)
+		return p.translate(m.GuildID, "The tag could not be saved.")
+	}
+	return p.translate(m.GuildID, "Added tag %q.", name)
+}
+
+func (p *plugin) validText(guildID, text string) string {
+	switch {
+	case text == "":
+		return p.translate(guildID, "A tag needs some text.")
+	case len([]rune(text)) > maxText:
+		return p.translate(guildID, "Tags can be at most %d characters long.", maxText)
+	}
+	return ""
+}
+
+func (p *plugin) edit(s bot.Session, m *dg.Message, name, text string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.get(m.GuildID, name)
+	switch {
+	case t == nil:
+		return p.translate(m.GuildID, "No tag named %q.", name)
+	case !p.canChange(s, m, t):
+		return p.translate(m.GuildID, "Only the person who added a tag, or someone who can manage messages, can change it.")
+	}
+	if why := p.validText(m.GuildID, text); why != "" {
+		return why
+	}
+
+	t.Text = text
+	t.Edited = time.Now()
+	err := p.db.Set(key(m.GuildID, name), t)
+	if err != nil {
+		p.logf("[tags] %v", err)
+		return p.translate(m.GuildID, "The tag could not be saved.")
+	}
+	return p.translate(m.GuildID, "Edited tag %q.", name)
+}
+
+func (p *plugin) remove(s bot.Session, m *dg.Message, name string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.get(m.GuildID, name)
+	switch {
+	case t == nil:
+		return p.translate(m.GuildID, "No tag named %q.", name)
+	case !p.canChange(s, m, t):
+		return p.translate(m.GuildID, "Only the person who added a tag, or someone who can manage messages, can change it.")
+	}
+
+	err := p.db.Delete(key(m.GuildID, name))
+	if err != nil {
+		p.logf("[tags] %v", err)
+		return p.translate(m.GuildID, "The tag could not be deleted.")
+	}
+	return p.translate(m.GuildID, "Deleted tag %q.", name)
+}
+
+func (p *plugin) listText(guildID string) string {
+	tags := p.list(guildID)
+	if len(tags) == 0 {
+		return p.translate(guildID, "There are no tags.")
+	}
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+	return p.translate(guildID, "Tags: %s", strings.Join(names, ", "))
+}
+
+func (p *plugin) info(guildID, name string) string {
+	t := p.get(guildID, name)
+	if t == nil {
+		return p.translate(guildID, "No tag named %q.", name)
+	}
+	text := p.translate(guildID, "Tag %q was added by %s on %s and has been used %d times.",
+		t.Name, t.OwnerName, t.Created.Format("2006-01-02"), t.Uses)
+	if !t.Edited.IsZero() {
+		text += " " + p.translate(guildID, "It was last edited on %s.", t.Edited.Format("2006-01-02"))
+	}
+	return text
+}
+
+// send sends a tag's text, rendered with args, and counts the use.
+func (p *plugin) send(s bot.Session, m *dg.Message, t *tag, args string) {
+	// tags must not be a way to mention everyone
+	err := p.reply(s, m, &dg.MessageSend{
+		Content:         p.render(s, m, t.Text, args),
+		AllowedMentions: &dg.MessageAllowedMentions{},
+	})
+	if err != nil {
+		p.logf("[tags] %v", err)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t = p.get(m.GuildID, t.Name); t != nil {
+		t.Uses++
+		err = p.db.Set(key(m.GuildID, t.Name), t)
+		if err != nil {
+			p.logf("[tags] %v", err)
+		}
+	}
+}
+
+// source provides each tag as a command in its guild.
+type source struct {
+	*plugin
+}
+
+func (source) Name() string {
+	return "server tags"
+}
+
+func (src source) Command(guildID, name string) bot.Command {
+	if t := src.get(guildID, name); t != nil {
+		return tagCommand{src.plugin, t}
+	}
+	return nil
+}
+
+func (src source) Commands(guildID string) []bot.Command {
+	if guildID == "" {
+		return nil
+	}
+	var cmds []bot.Command
+	for _, t := range src.list(guildID) {
+		cmds = append(cmds, tagCommand{src.plugin, t})
+	}
+	return cmds
+}
+
+// tagCommand sends a tag.
+type tagCommand struct {
+	p *plugin
+	*tag
+}
+
+func (c tagCommand) Name() string {
+	return c.tag.Name
+}
+
+func (c tagCommand) Comment() string {
+	return strings.TrimSpace(strings.SplitN(c.Text, "\n", 2)[0])
+}
+
+func (c tagCommand) Usage() []string {
+	return []string{c.tag.Name}
+}
+
+func (c tagCommand) Description() string {
+	return "A tag added by " + c.OwnerName + ". For more about it, use " + c.p.sigil() + "tag info " + c.tag.Name
+}
+
+func (c tagCommand) Execute(s bot.Session, m *dg.Message) {
+	c.p.send(s, m, c.tag, m.Content)
+}
@@ -0,0 +1,107 @@
+package tags
+
+import (
+	"strings"
+	"testing"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestTags(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	b.AddCommand(bot.SimpleCommand("roll", func(bot.Session, *dg.Message) {}, bot.SimpleCommandInfo{}))
+	if err := b.AddPlugin(Plugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		guild, author, content string
+		perms                  int64
+		want                   string
+	}{
+		{"g", "alice", "!tag list", dg.PermissionAll, "There are no tags."},
+		{"g", "alice", "!tag add rules Be nice.\nNo spam.", dg.PermissionAll, `Added tag "rules".`},
+		{"g", "alice", "!rules", dg.PermissionAll, "Be nice.\nNo spam."},
+		{"g", "alice", "!tag rules", dg.PermissionAll, "Be nice.\nNo spam."},
+		{"g", "alice", "!Rules", dg.PermissionAll, "Be nice.\nNo spam."},
+		{"h", "alice", "!rules", dg.PermissionAll, ""},
+		{"g", "bob", "!tag add Rules again", dg.PermissionAll, `There is already a tag named "rules".`},
+		{"g", "bob", "!tag add roll 4", dg.PermissionAll, `There is already a command named "roll".`},
+		{"g", "bob", "!tag add list 4", dg.PermissionAll, `"list" cannot be used as a tag name.`},
+		{"g", "bob", "!tag add a/b 4", dg.PermissionAll, "Tag names may only have letters, digits, dashes and underscores."},
+		{"g", "bob", "!tag add faq", dg.PermissionAll, "A tag needs some text."},
+		{"g", "bob", "!tag edit rules Be mean.", 0, "Only the person who added a tag, or someone who can manage messages, can change it."},
+		{"g", "bob", "!tag edit rules Be kind.", dg.PermissionManageMessages, `Edited tag "rules".`},
+		{"g", "alice", "!rules", 0, "Be kind."},
+		{"g", "bob", "!tag add faq Read the rules.", 0, `Added tag "faq".`},
+		{"g", "bob", "!tag list", 0, "Tags: faq, rules"},
+		{"g", "bob", "!tag delete rules", 0, "Only the person who added a tag, or someone who can manage messages, can change it."},
+		{"g", "owner", "!tag delete rules", 0, `Deleted tag "rules".`},
+		{"g", "bob", "!tag info rules", 0, `No tag named "rules".`},
+		{"g", "bob", "!tag info @everyone", 0, `No tag named "@everyone".`},
+		{"g", "bob", "!tag add greet Hi {args}, says {user}.", 0, `Added tag "greet".`},
+		{"g", "bob", "!greet alice", 0, "Hi alice, says userbob."},
+		{"", "bob", "!tag list", 0, "This command can only be used in a server."},
+		{"", "bob", "!faq", 0, ""},
+	}
+	for _, tt := range tests {
+		s := bottest.NewSession()
+		s.Permissions = map[string]int64{"c": tt.perms}
+		m := bottest.Message("c", tt.author, tt.content)
+		m.GuildID = tt.guild
+		b.Dispatch(s, "self", m)
+
+		got := strings.Join(s.Contents(), "\n")
+		if got != tt.want {
+			t.Errorf("%s %q: got %q, want %q", tt.author, tt.content, got, tt.want)
+		}
+		for _, am := range s.AllowedMentions {
+			if tt.guild != "" && (am == nil || len(am.Parse) != 0) {
+				t.Errorf("%s %q: mentions allowed", tt.author, tt.content)
+			}
+		}
+	}
+
+	m := bottest.Message("c", "bob", "!tag info faq")
+	m.GuildID = "g"
+	s := bottest.NewSession()
+	b.Dispatch(s, "self", m)
+	if got := s.Contents(); len(got) != 1 || !strings.HasPrefix(got[0], `Tag "faq" was added by userbob on `) {
+		t.Errorf("got %q, want info about faq", got)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "owner", "sigil": "!"})
+	if err := b.AddPlugin(Plugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	s := bottest.NewSession()
+	for _, content := range []string{"!tag add zzz first line\nsecond line", "!help"} {
+		m := bottest.Message("c", "alice", content)
+		m.GuildID = "g"
+		b.Dispatch(s, "self", m)
+	}
+
+	embeds := s.Embeds()
+	if len(embeds) != 1 {
+		t.Fatalf("got %d embeds, want 1", len(embeds))
+	}
+	fields := embeds[0].Fields
+	last := fields[len(fields)-1]
+	if last.Name != "server tags" || last.Value != "`!zzz` first line" {
+		t.Errorf("got last section %q: %q, want the tag", last.Name, last.Value)
+	}
+
+	if err := b.UnloadPlugin("tags"); err != nil {
+		t.Fatal(err)
+	}
+	m := bottest.Message("c", "alice", "!zzz")
+	m.GuildID = "g"
+	if b.Dispatch(s, "self", m) {
+		t.Error("tag used after unloading the plugin")
+	}
+}
@@ -12,41 +12,70 @@ import (
 )
 
 func Plugin() bot.Plugin {
-	return plugin
+	return &plugin{
+		cooldown: defaultCooldown,
+		message:  defaultMessage,
+		weebs:    make(map[string]time.Time),
+	}
 }
 
-var plugin = bot.SimplePlugin("weeb", func(b *bot.Bot) error {
-	logf = b.Logf
-	sigil = b.Sigil()
-	err := configure(b.Config)
+const (
+	defaultCooldown = 5 * time.Minute
+	defaultMessage  = "{user} is a filthy WEEB!"
+)
+
+// plugin remembers, for one bot, when each user was last called a weeb.
+type plugin struct {
+	cooldown  time.Duration
+	logf      func(format string, v ...interface{})
+	translate func(guildID, msg string, args ...interface{}) string
+	sigil     string
+
+	// message is sent about weebs, as a template
+	message        string
+	templateVars   func(s bot.Session, m *dg.Message, args string) map[string]string
+	renderTemplate func(text string, vars map[string]string) string
+
+	mutex sync.Mutex
+	weebs map[string]time.Time
+}
+
+func (p *plugin) Name() string {
+	return "weeb"
+}
+
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.translate = b.T
+	p.sigil = b.Sigil()
+	p.templateVars = b.TemplateVars
+	p.renderTemplate = b.RenderTemplate
+	err := p.configure(b.Config)
 	if err != nil {
 		return err
 	}
-	b.Session.AddHandler(handle)
+	b.OnMessage(p.handle)
 	return nil
-})
-
-var (
-	cooldown = 5 * time.Minute
-	logf     func(format string, v ...interface{})
-	sigil    string
-
-	mutex sync.Mutex
-	weebs = make(map[string]time.Time)
-)
+}
 
-func configure(c *config.Config) error {
+func (p *plugin) configure(c *config.Config) error {
 	if c.Exists("weeb") {
 		var x struct {
 			Cooldown string
+			Message  string
 		}
 		err := c.Get("weeb", &x)
 		if err != nil {
 			return err
 		}
-		cooldown, err = time.ParseDuration(x.Cooldown)
-		if err != nil {
-			return err
+		if x.Cooldown != "" {
+			p.cooldown, err = time.ParseDuration(x.Cooldown)
+			if err != nil {
+				return err
+			}
+		}
+		if x.Message != "" {
+			p.message = x.Message
 		}
 	}
 	return nil
@@ -61,13 +90,18 @@ func containsJapanese(s string) bool {
 	return false
 }
 
-func getDisplayName(st *dg.State, channelID, userID string) (string, error) {
-	ch, err := st.Channel(channelID)
+// getDisplayName returns the name shown for the author of a message,
+// which is their nickname in a guild or their username in a DM.
+func getDisplayName(s bot.Session, m *dg.Message) (string, error) {
+	ch, err := s.Channel(m.ChannelID)
 	if err != nil {
 		return "", err
 	}
+	if ch.GuildID == "" {
+		return m.Author.Username, nil
+	}
 
-	mem, err := st.Member(ch.GuildID, userID)
+	mem, err := s.GuildMember(ch.GuildID, m.Author.ID)
 	if err != nil {
 		return "", err
 	}
@@ -78,31 +112,34 @@ func getDisplayName(st *dg.State, channelID, userID string) (string, error) {
 	return mem.User.Username, nil
 }
 
-func handle(s *dg.Session, mc *dg.MessageCreate) {
-	m := mc.Message
-	if m.Author.ID == s.State.User.ID ||
-		strings.HasPrefix(m.Content, sigil) ||
-		!containsJapanese(m.Content) {
+func (p *plugin) handle(e *bot.MessageEvent) {
+	p.respond(e.Session, e.Message)
+}
+
+func (p *plugin) respond(s bot.Session, m *dg.Message) {
+	if strings.HasPrefix(m.Content, p.sigil) || !containsJapanese(m.Content) {
 		return
 	}
 
-	mutex.Lock()
-	defer mutex.Unlock()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 
-	last := weebs[m.Author.ID]
-	weebs[m.Author.ID] = time.Now()
-	if time.Since(last) < cooldown {
+	last := p.weebs[m.Author.ID]
+	p.weebs[m.Author.ID] = time.Now()
+	if time.Since(last) < p.cooldown {
 		return
 	}
 
-	name, err := getDisplayName(s.State, m.ChannelID, m.Author.ID)
+	name, err := getDisplayName(s, m)
 	if err != nil {
-		logf("[weeb] %v", err)
+		p.logf("[weeb] %v", err)
 		return
 	}
 
-	_, err = s.ChannelMessageSend(m.ChannelID, name+" is a filthy WEEB!")
+	vars := p.templateVars(s, m, m.Content)
+	vars["user"] = name
+	_, err = s.ChannelMessageSend(m.ChannelID, p.renderTemplate(p.translate(m.GuildID, p.message), vars))
 	if err != nil {
-		logf("[weeb] %v", err)
+		p.logf("[weeb] %v", err)
 	}
 }
@@ -0,0 +1,84 @@
+package weeb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestRespond(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		author  string
+		content string
+		last    time.Duration // time since the author's last message, zero for none
+		want    []string
+	}{
+		{"japanese", "c", "nick", "こんにちは", 0, []string{"Nick is a filthy WEEB!"}},
+		{"no nickname", "c", "plain", "日本", 0, []string{"userplain is a filthy WEEB!"}},
+		{"english", "c", "nick", "hello", 0, []string{}},
+		{"command", "c", "nick", "!say こんにちは", 0, []string{}},
+		{"cooldown", "c", "nick", "カタカナ", time.Minute, []string{}},
+		{"after cooldown", "c", "nick", "カタカナ", time.Hour, []string{"Nick is a filthy WEEB!"}},
+		{"unknown member", "c", "stranger", "こんにちは", 0, []string{}},
+		{"dm", "dm", "stranger", "こんにちは", 0, []string{"userstranger is a filthy WEEB!"}},
+	}
+
+	b := bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &plugin{
+				cooldown:       5 * time.Minute,
+				logf:           t.Logf,
+				translate:      b.T,
+				sigil:          "!",
+				message:        defaultMessage,
+				templateVars:   b.TemplateVars,
+				renderTemplate: b.RenderTemplate,
+				weebs:          make(map[string]time.Time),
+			}
+			if tt.last != 0 {
+				p.weebs[tt.author] = time.Now().Add(-tt.last)
+			}
+
+			s := bottest.NewSession(&dg.Channel{ID: "c", GuildID: "g"}, &dg.Channel{ID: "dm", Type: dg.ChannelTypeDM})
+			s.AddMember(&dg.Member{GuildID: "g", Nick: "Nick", User: &dg.User{ID: "nick", Username: "usernick"}})
+			s.AddMember(&dg.Member{GuildID: "g", User: &dg.User{ID: "plain", Username: "userplain"}})
+			p.respond(s, bottest.Message(tt.channel, tt.author, tt.content))
+
+			if got := s.Contents(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstances(t *testing.T) {
+	bots := []struct {
+		message, want string
+	}{
+		{"{user} is a weeb", "userplain is a weeb"},
+		{"{user} likes anime", "userplain likes anime"},
+	}
+
+	var plugins []*plugin
+	for _, tt := range bots {
+		b := bottest.NewBot(t, map[string]interface{}{"owner": "o", "sigil": "!", "weeb": map[string]string{"message": tt.message}})
+		if err := b.AddPlugin(Plugin()); err != nil {
+			t.Fatal(err)
+		}
+		plugins = append(plugins, b.GetPlugin("weeb").(*plugin))
+	}
+
+	for i, p := range plugins {
+		s := bottest.NewSession(&dg.Channel{ID: "dm", Type: dg.ChannelTypeDM})
+		p.respond(s, bottest.Message("dm", "plain", "こんにちは"))
+		if got, want := s.Contents(), []string{bots[i].want}; !reflect.DeepEqual(got, want) {
+			t.Errorf("bot %d: got %q, want %q", i, got, want)
+		}
+	}
+}
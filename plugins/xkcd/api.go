@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/njhanley/stoopid/bot"
 )
 
 type Info struct {
@@ -13,17 +17,27 @@ type Info struct {
 	Img              string
 }
 
+var (
+	baseURL   = "https://xkcd.com/"
+	randomURL = "https://c.xkcd.com/random/comic"
+)
+
+// comics caches comics by number, as they do not change once posted.
+// It is shared by every bot the plugin is loaded into.
+var comics sync.Map // string to *Info
+
 func parse(b []byte) (*Info, error) {
 	var x Info
 	err := json.Unmarshal(b, &x)
 	if err != nil {
 		return nil, err
 	}
+	comics.Store(strconv.Itoa(x.Num), &x)
 	return &x, nil
 }
 
 func get(url string) ([]byte, error) {
-	r, err := http.Get(url)
+	r, err := bot.HTTPClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +47,11 @@ func get(url string) ([]byte, error) {
 
 // empty num for current comic
 func Get(num string) (*Info, error) {
-	url := "https://xkcd.com/"
+	if info, ok := comics.Load(num); ok {
+		return info.(*Info), nil
+	}
+
+	url := baseURL
 	if num != "" {
 		url += num + "/"
 	}
@@ -54,7 +72,7 @@ var noRedirectClient = &http.Client{
 }
 
 func GetRandom() (*Info, error) {
-	r, err := noRedirectClient.Get("https://c.xkcd.com/random/comic")
+	r, err := noRedirectClient.Get(randomURL)
 	if err != nil {
 		return nil, err
 	}
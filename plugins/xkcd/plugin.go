@@ -4,32 +4,51 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 
 	dg "github.com/bwmarrin/discordgo"
 	"github.com/njhanley/stoopid/bot"
 )
 
 func Plugin() bot.Plugin {
-	return plugin
+	return new(plugin)
 }
 
-var plugin = bot.SimplePlugin("xkcd", func(b *bot.Bot) error {
-	logf = b.Logf
-	b.AddCommand(command)
-	return nil
-})
+type plugin struct {
+	logf     func(format string, v ...interface{})
+	newEmbed func(guildID string) *bot.EmbedBuilder
+}
 
-var logf func(format string, v ...interface{})
+func (p *plugin) Name() string {
+	return "xkcd"
+}
 
-var command = bot.SimpleCommand("xkcd", execute, bot.SimpleCommandInfo{
+func (p *plugin) Load(b *bot.Bot) error {
+	p.logf = b.Logf
+	p.newEmbed = b.NewEmbed
+	command := bot.SimpleCommand("xkcd", p.execute, commandInfo)
+	b.AddCommand(bot.ToArgumentCommand(command, bot.Argument{
+		Name:        "comic",
+		Description: "a comic number or random",
+		Complete: func(partial string) []string {
+			if strings.HasPrefix("random", partial) {
+				return []string{"random"}
+			}
+			return nil
+		},
+	}))
+	return nil
+}
+
+var commandInfo = bot.SimpleCommandInfo{
 	Comment:     "get xkcd comics",
 	Usage:       []string{"xkcd", "xkcd <number>", "xkcd random"},
 	Description: "Get xkcd comics.",
-})
+}
 
 var numRegexp = regexp.MustCompile("^[1-9][0-9]*$")
 
-func execute(s *dg.Session, m *dg.Message) {
+func (p *plugin) execute(s bot.Session, m *dg.Message) {
 	var (
 		info *Info
 		err  error
@@ -44,22 +63,17 @@ func execute(s *dg.Session, m *dg.Message) {
 		err = fmt.Errorf("invalid argument %q", m.Content)
 	}
 	if err != nil {
-		logf("[xkcd] %v", err)
+		p.logf("[xkcd] %v", err)
 		return
 	}
 
-	msg := &dg.MessageEmbed{
-		URL:   "https://xkcd.com/" + strconv.Itoa(info.Num) + "/",
-		Title: "xkcd: " + info.Title,
-		Image: &dg.MessageEmbedImage{
-			URL: info.Img,
-		},
-		Footer: &dg.MessageEmbedFooter{
-			Text: fmt.Sprintf("#%d, posted %s-%s-%s", info.Num, info.Year, info.Month, info.Day),
-		},
-	}
-	_, err = s.ChannelMessageSendEmbed(m.ChannelID, msg)
+	err = p.newEmbed(m.GuildID).
+		URL(baseURL+strconv.Itoa(info.Num)+"/").
+		Title("xkcd: "+info.Title).
+		Image(info.Img).
+		FooterNote(fmt.Sprintf("#%d, posted %s-%s-%s", info.Num, info.Year, info.Month, info.Day)).
+		Send(s, m)
 	if err != nil {
-		logf("[xkcd] %v", err)
+		p.logf("[xkcd] %v", err)
 	}
 }
@@ -0,0 +1,72 @@
+package xkcd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/njhanley/stoopid/bot/bottest"
+)
+
+func TestExecute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/info.0.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"num":2000,"title":"Latest","img":"https://imgs.xkcd.com/latest.png","year":"2018","month":"5","day":"30"}`))
+	})
+	mux.HandleFunc("/614/info.0.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"num":614,"title":"Woodpecker","img":"https://imgs.xkcd.com/woodpecker.png","year":"2009","month":"7","day":"27"}`))
+	})
+	mux.HandleFunc("/random/comic", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, baseURL+"614/", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	oldBase, oldRandom := baseURL, randomURL
+	baseURL, randomURL = srv.URL+"/", srv.URL+"/random/comic"
+	defer func() { baseURL, randomURL = oldBase, oldRandom }()
+
+	tests := []struct {
+		content string
+		title   string // empty for no reply
+		footer  string
+	}{
+		{"", "xkcd: Latest", "#2000, posted 2018-5-30 · stoopid"},
+		{"614", "xkcd: Woodpecker", "#614, posted 2009-7-27 · stoopid"},
+		{"random", "xkcd: Woodpecker", "#614, posted 2009-7-27 · stoopid"},
+		{"0614", "", ""},
+		{"latest", "", ""},
+	}
+
+	p := &plugin{
+		logf: t.Logf,
+		newEmbed: bottest.NewBot(t, map[string]interface{}{
+			"owner":  "o",
+			"sigil":  "!",
+			"embeds": map[string]interface{}{"footer": "stoopid"},
+		}).NewEmbed,
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			s := bottest.NewSession()
+			p.execute(s, bottest.Message("c", "u", tt.content))
+
+			embeds := s.Embeds()
+			if tt.title == "" {
+				if len(embeds) != 0 {
+					t.Errorf("got %d embeds, want none", len(embeds))
+				}
+				return
+			}
+			if len(embeds) != 1 {
+				t.Fatalf("got %d embeds, want 1", len(embeds))
+			}
+			if embeds[0].Title != tt.title {
+				t.Errorf("got title %q, want %q", embeds[0].Title, tt.title)
+			}
+			if embeds[0].Footer == nil || embeds[0].Footer.Text != tt.footer {
+				t.Errorf("got footer %+v, want %q", embeds[0].Footer, tt.footer)
+			}
+		})
+	}
+}
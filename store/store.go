@@ -0,0 +1,141 @@
+// Package store provides a persistent key-value store.
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Store exposes a JSON file as a read-write key-value store.
+// Every change is written to the file before it returns.
+type Store struct {
+	mu   sync.RWMutex
+	data map[string]json.RawMessage
+
+	filename string // immutable
+}
+
+// New creates a Store backed by a file, loading the file if it exists.
+// If filename is empty, the store is kept in memory only.
+func New(filename string) (*Store, error) {
+	s := &Store{data: make(map[string]json.RawMessage), filename: filename}
+	if filename == "" {
+		return s, nil
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read store")
+	}
+
+	err = json.Unmarshal(b, &s.data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal store")
+	}
+	return s, nil
+}
+
+// Exists reports whether a key exists.
+func (s *Store) Exists(key string) bool {
+	s.mu.RLock()
+	_, ok := s.data[key]
+	s.mu.RUnlock()
+	return ok
+}
+
+// Get unmarshals a key's value into an interface using the same rules as json.Unmarshal.
+// It returns an error if the key is not set.
+func (s *Store) Get(key string, value interface{}) error {
+	s.mu.RLock()
+	b, ok := s.data[key]
+	s.mu.RUnlock()
+	if !ok {
+		return errors.Errorf("key %q not found in store", key)
+	}
+
+	err := json.Unmarshal(b, value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to unmarshal key %q", key)
+	}
+
+	return nil
+}
+
+// Set marshals a value and stores it under a key.
+func (s *Store) Set(key string, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal key %q", key)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = b
+	return s.save()
+}
+
+// Delete removes a key.
+func (s *Store) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[key]; !ok {
+		return nil
+	}
+	delete(s.data, key)
+	return s.save()
+}
+
+// Keys returns the keys beginning with prefix, sorted.
+func (s *Store) Keys(prefix string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []string
+	for key := range s.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// save writes the store to its file, replacing the file
+// only once it has been written in full.
+func (s *Store) save() error {
+	if s.filename == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(s.data, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal store")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.filename), filepath.Base(s.filename)+".*")
+	if err != nil {
+		return errors.Wrap(err, "failed to write store")
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.filename)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "failed to write store")
+	}
+	return nil
+}
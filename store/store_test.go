@@ -0,0 +1,51 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "state.json")
+
+	s, err := New(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("a/1", []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("a/2", "two"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("b", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new store sees the changes
+	s, err = New(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	if err := s.Get("a/1", &got); err != nil || !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("got %v, %v, want [1 2]", got, err)
+	}
+	if s.Exists("b") {
+		t.Error("deleted key exists")
+	}
+	if keys := s.Keys("a/"); !reflect.DeepEqual(keys, []string{"a/1", "a/2"}) {
+		t.Errorf("got keys %q", keys)
+	}
+}
@@ -0,0 +1,328 @@
+// Package template renders the text of custom responses, which may hold
+// placeholders filled in when the text is sent:
+//
+//	{name}              the value of a variable, such as {user} or {args}
+//	{choose:a|b|c}      one of the alternatives, chosen at random
+//	{roll:2d6+1}        the total of a roll of dice
+//
+// The alternatives of {choose} may hold placeholders themselves.
+// A backslash makes the character after it literal, so \{ is a brace
+// and \| a bar that does not separate alternatives. Braces that do
+// not start a placeholder, and placeholders naming variables that are
+// not set, are left as they are.
+//
+// Templates cannot do anything but fill in text, and rendering one
+// stops with ErrBudget once it has taken too many steps or produced
+// too much text, so templates written by users are safe to render.
+package template
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBudget is returned when rendering a template exceeds its budget.
+var ErrBudget = errors.New("template: execution budget exceeded")
+
+// MaxDepth is how deeply placeholders may be nested.
+const MaxDepth = 8
+
+const (
+	maxDice  = 1000
+	maxSides = 1000000
+)
+
+// Budget limits the work done rendering a template.
+type Budget struct {
+	Steps  int // placeholders filled in and dice rolled
+	Output int // bytes of text produced
+}
+
+// DefaultBudget is used when rendering with a zero Budget.
+var DefaultBudget = Budget{Steps: 1000, Output: 4000}
+
+// Env is what a template is rendered with.
+type Env struct {
+	Vars   map[string]string // by name
+	Rand   *rand.Rand        // nil for a source shared by all templates
+	Budget Budget            // zero for DefaultBudget
+}
+
+// Template is a parsed template.
+type Template struct {
+	nodes []node
+}
+
+type node interface {
+	render(*state) error
+}
+
+type state struct {
+	Env
+	steps int
+	out   strings.Builder
+}
+
+var (
+	sharedMu   sync.Mutex
+	sharedRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func (st *state) intn(n int) int {
+	if st.Rand != nil {
+		return st.Rand.Intn(n)
+	}
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	return sharedRand.Intn(n)
+}
+
+// step counts a step against the budget.
+func (st *state) step() error {
+	st.steps++
+	if st.steps > st.Budget.Steps {
+		return ErrBudget
+	}
+	return nil
+}
+
+func (st *state) write(s string) error {
+	if st.out.Len()+len(s) > st.Budget.Output {
+		return ErrBudget
+	}
+	st.out.WriteString(s)
+	return nil
+}
+
+type text string
+
+func (t text) render(st *state) error {
+	return st.write(string(t))
+}
+
+type variable string
+
+func (v variable) render(st *state) error {
+	if err := st.step(); err != nil {
+		return err
+	}
+	value, ok := st.Vars[string(v)]
+	if !ok {
+		value = "{" + string(v) + "}"
+	}
+	return st.write(value)
+}
+
+type choice [][]node
+
+func (c choice) render(st *state) error {
+	if err := st.step(); err != nil {
+		return err
+	}
+	return renderNodes(st, c[st.intn(len(c))])
+}
+
+type roll struct {
+	dice, sides, modifier int
+}
+
+func (r roll) render(st *state) error {
+	total := r.modifier
+	for i := 0; i < r.dice; i++ {
+		if err := st.step(); err != nil {
+			return err
+		}
+		total += st.intn(r.sides) + 1
+	}
+	return st.write(strconv.Itoa(total))
+}
+
+func renderNodes(st *state, nodes []node) error {
+	for _, n := range nodes {
+		if err := n.render(st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Render renders the template.
+func (t *Template) Render(env Env) (string, error) {
+	if env.Budget == (Budget{}) {
+		env.Budget = DefaultBudget
+	}
+	st := &state{Env: env}
+	if err := renderNodes(st, t.nodes); err != nil {
+		return "", err
+	}
+	return st.out.String(), nil
+}
+
+// Render parses and renders a template.
+func Render(s string, env Env) (string, error) {
+	t, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(env)
+}
+
+// Parse parses a template.
+func Parse(s string) (*Template, error) {
+	p := &parser{s: s}
+	nodes, err := p.parse(0, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{nodes}, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+// parse parses nodes until the end of the text or, if in an alternative
+// of a choice, the bar or brace ending it, which is not consumed.
+func (p *parser) parse(depth int, alternative bool) ([]node, error) {
+	var (
+		nodes []node
+		buf   strings.Builder
+	)
+	flush := func() {
+		if buf.Len() > 0 {
+			nodes = append(nodes, text(buf.String()))
+			buf.Reset()
+		}
+	}
+
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.s) && strings.IndexByte(`\{}|`, p.s[p.pos+1]) >= 0:
+			buf.WriteByte(p.s[p.pos+1])
+			p.pos += 2
+		case alternative && (c == '|' || c == '}'):
+			flush()
+			return nodes, nil
+		case c == '{':
+			n, err := p.placeholder(depth)
+			if err != nil {
+				return nil, err
+			}
+			if n == nil {
+				buf.WriteByte(c)
+				p.pos++
+				continue
+			}
+			flush()
+			nodes = append(nodes, n)
+		default:
+			buf.WriteByte(c)
+			p.pos++
+		}
+	}
+	if alternative {
+		return nil, errors.New("template: unclosed {choose")
+	}
+	flush()
+	return nodes, nil
+}
+
+// placeholder parses the placeholder starting at the current brace, or
+// returns nil without consuming anything if the brace does not start one.
+func (p *parser) placeholder(depth int) (node, error) {
+	end := p.pos + 1
+	for end < len(p.s) && isNameByte(p.s[end]) {
+		end++
+	}
+	name := p.s[p.pos+1 : end]
+	if name == "" || end == len(p.s) {
+		return nil, nil
+	}
+
+	switch {
+	case p.s[end] == '}':
+		p.pos = end + 1
+		return variable(name), nil
+	case p.s[end] != ':':
+		return nil, nil
+	case name == "choose":
+		if depth >= MaxDepth {
+			return nil, errors.New("template: placeholders nested too deeply")
+		}
+		p.pos = end + 1
+		var c choice
+		for {
+			alt, err := p.parse(depth+1, true)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, alt)
+			// parse stops at a bar or a brace
+			sep := p.s[p.pos]
+			p.pos++
+			if sep == '}' {
+				return c, nil
+			}
+		}
+	case name == "roll":
+		n := strings.IndexByte(p.s[end:], '}')
+		if n < 0 {
+			return nil, errors.New("template: unclosed {roll")
+		}
+		r, err := parseRoll(strings.TrimSpace(p.s[end+1 : end+n]))
+		if err != nil {
+			return nil, err
+		}
+		p.pos = end + n + 1
+		return r, nil
+	}
+	return nil, nil
+}
+
+func isNameByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
+
+// parseRoll parses dice like 2d6, d20 or 3d8-2.
+func parseRoll(s string) (roll, error) {
+	bad := errors.Errorf("template: bad dice %q", s)
+	d := strings.IndexAny(s, "dD")
+	if d < 0 {
+		return roll{}, bad
+	}
+
+	r := roll{dice: 1}
+	if d > 0 {
+		n, err := strconv.Atoi(s[:d])
+		if err != nil {
+			return roll{}, bad
+		}
+		r.dice = n
+	}
+	sides := s[d+1:]
+	if m := strings.IndexAny(sides, "+-"); m >= 0 {
+		n, err := strconv.Atoi(sides[m:])
+		if err != nil || n < -maxSides || n > maxSides {
+			return roll{}, bad
+		}
+		r.modifier = n
+		sides = sides[:m]
+	}
+	n, err := strconv.Atoi(sides)
+	if err != nil {
+		return roll{}, bad
+	}
+	r.sides = n
+
+	if r.dice < 1 || r.dice > maxDice || r.sides < 1 || r.sides > maxSides {
+		return roll{}, errors.Errorf("template: dice %q out of bounds", s)
+	}
+	return r, nil
+}
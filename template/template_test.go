@@ -0,0 +1,103 @@
+package template
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	vars := map[string]string{"user": "Nick", "args": "why {user}?"}
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"plain text", "plain text"},
+		{"hi {user}!", "hi Nick!"},
+		{"{args}", "why {user}?"},
+		{"{missing} stays", "{missing} stays"},
+		{"{ not a placeholder }", "{ not a placeholder }"},
+		{"{note: braces}", "{note: braces}"},
+		{`\{user\} and \\`, `{user} and \`},
+		{`a \d stays`, `a \d stays`},
+		{"{choose:only {user}}", "only Nick"},
+		{"{choose:a|a|a}", "a"},
+		{`{choose:x\|y}`, "x|y"},
+		{"{choose:{choose:{user}}}", "Nick"},
+		{"{roll:1d1}", "1"},
+		{"{roll: 3d1+2 }", "5"},
+		{"{roll:d1-1}", "0"},
+	}
+	for _, tt := range tests {
+		got, err := Render(tt.in, Env{Vars: vars})
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderRandom(t *testing.T) {
+	tmpl, err := Parse("{choose:a|b|c} {roll:2d6}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(1))
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		got, err := tmpl.Render(Env{Rand: r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := strings.Fields(got)
+		seen[fields[0]] = true
+		if n, err := strconv.Atoi(fields[1]); err != nil || n < 2 || n > 12 {
+			t.Errorf("got %q, want a total of 2d6", got)
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("chose %v, want all of a, b and c", seen)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"{choose:a|b",
+		"{roll:2d6",
+		"{roll:lots}",
+		"{roll:0d6}",
+		"{roll:1001d6}",
+		"{roll:2d0}",
+		strings.Repeat("{choose:", MaxDepth+1) + strings.Repeat("}", MaxDepth+1),
+	} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("%q: parsed without error", in)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		in     string
+		budget Budget
+	}{
+		{"{roll:1000d6}", Budget{Steps: 100, Output: 100}},
+		{strings.Repeat("{user}", 11), Budget{Steps: 10, Output: 1000}},
+		{strings.Repeat("x", 11), Budget{Steps: 10, Output: 10}},
+		{"{user}{user}", Budget{Steps: 10, Output: 7}},
+	}
+	for _, tt := range tests {
+		_, err := Render(tt.in, Env{Vars: map[string]string{"user": "Nick"}, Budget: tt.budget})
+		if err != ErrBudget {
+			t.Errorf("%q: got %v, want %v", tt.in, err, ErrBudget)
+		}
+	}
+
+	if _, err := Render(strings.Repeat("{roll:1000d6}", 2), Env{}); err != ErrBudget {
+		t.Errorf("got %v, want %v with the default budget", err, ErrBudget)
+	}
+}